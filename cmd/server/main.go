@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 
 	"github.com/amard/pemilo-golang/internal/config"
+	"github.com/amard/pemilo-golang/internal/delivery"
 	"github.com/amard/pemilo-golang/internal/handler"
 	"github.com/amard/pemilo-golang/internal/middleware"
 	"github.com/amard/pemilo-golang/internal/repository"
@@ -48,16 +50,65 @@ func main() {
 	ballotRepo := repository.NewBallotRepo(db)
 	auditLogRepo := repository.NewAuditLogRepo(db)
 	orderRepo := repository.NewOrderRepo(db)
+	deliveryRepo := repository.NewDeliveryRepo(db)
+	lockoutRepo := repository.NewLockoutRepo(db)
+	registrationRepo := repository.NewRegistrationRepo(db)
+	proxyRepo := repository.NewProxyRepo(db)
+	importJobRepo := repository.NewImportJobRepo(db)
+	directoryRepo := repository.NewDirectoryRepo(db)
+	templateRepo := repository.NewTemplateRepo(db)
+
+	// Token delivery channels (email, WhatsApp, SMS)
+	deliveryRoutes, err := delivery.RoutesFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("invalid delivery configuration: %v", err)
+	}
 
 	// Services
 	authService := service.NewAuthService(userRepo, cfg)
-	eventService := service.NewEventService(eventRepo, auditLogRepo)
+	eventService := service.NewEventService(db, eventRepo, voterRepo, voterTokenRepo, auditLogRepo)
 	slateService := service.NewSlateService(slateRepo, eventRepo)
-	voterService := service.NewVoterService(voterRepo, voterTokenRepo, eventRepo, auditLogRepo)
-	voteService := service.NewVoteService(db, eventRepo, slateRepo, voterRepo, voterTokenRepo, ballotRepo)
+	voterService := service.NewVoterService(db, voterRepo, voterTokenRepo, eventRepo, auditLogRepo, cfg)
+	importJobService := service.NewImportJobService(importJobRepo, eventRepo, voterService)
+	directoryService := service.NewDirectoryService(db, directoryRepo, voterService)
+	eventCloneService := service.NewEventCloneService(db, eventRepo, slateRepo, voterRepo, templateRepo, auditLogRepo, cfg)
+	archiveService := service.NewArchiveService(db, eventRepo, orderRepo, voterRepo, voterTokenRepo, deliveryRepo, lockoutRepo,
+		registrationRepo, importJobRepo, auditLogRepo, cfg.PIIRetention)
+	registrationService := service.NewRegistrationService(db, registrationRepo, voterRepo, voterTokenRepo, eventRepo, auditLogRepo, cfg)
+	proxyService := service.NewProxyService(db, proxyRepo, voterRepo, eventRepo, auditLogRepo)
+	lockoutService := service.NewLockoutService(lockoutRepo, eventRepo, auditLogRepo, cfg)
+	voteService := service.NewVoteService(db, eventRepo, slateRepo, voterRepo, voterTokenRepo, ballotRepo, proxyRepo, auditLogRepo, lockoutService, cfg)
 	statsService := service.NewStatsService(ballotRepo, eventRepo)
 	auditService := service.NewAuditService(auditLogRepo, eventRepo)
 	paymentService := service.NewPaymentService(orderRepo, eventRepo, cfg)
+	deliveryService := service.NewDeliveryService(deliveryRepo, eventRepo, auditLogRepo, cfg, deliveryRoutes...)
+
+	// Hash the phone digits of voters stored before PHONE_LAST4 used a hash
+	if n, err := voterService.HashMissingPhones(context.Background()); err != nil {
+		log.Printf("failed to hash voter phones: %v", err)
+	} else if n > 0 {
+		log.Printf("hashed phone digits of %d voters", n)
+	}
+
+	// Pick up deliveries that were still queued when the last process stopped
+	if err := deliveryService.ResumeQueued(context.Background()); err != nil {
+		log.Printf("failed to resume queued deliveries: %v", err)
+	}
+
+	// Same for voter import jobs, including ones cut off mid-run
+	if err := importJobService.ResumeQueued(context.Background()); err != nil {
+		log.Printf("failed to resume import jobs: %v", err)
+	}
+
+	// Open and close events on their opens_at/closes_at
+	schedulerService := service.NewSchedulerService(db, eventRepo, auditLogRepo, cfg.SchedulerInterval)
+	schedulerService.Start(context.Background())
+
+	// Purge voter PII of events locked longer than the retention period
+	archiveService.StartRetention(context.Background())
+
+	// Drop failure records of voters who stopped failing before a lockout
+	lockoutService.StartPruning(context.Background())
 
 	// Handlers
 	authHandler := handler.NewAuthHandler(authService)
@@ -68,6 +119,14 @@ func main() {
 	statsHandler := handler.NewStatsHandler(statsService, cfg.JWTSecret)
 	paymentHandler := handler.NewPaymentHandler(paymentService)
 	auditLogHandler := handler.NewAuditLogHandler(auditService)
+	deliveryHandler := handler.NewDeliveryHandler(deliveryService)
+	lockoutHandler := handler.NewLockoutHandler(lockoutService)
+	registrationHandler := handler.NewRegistrationHandler(registrationService)
+	proxyHandler := handler.NewProxyHandler(proxyService)
+	importJobHandler := handler.NewImportJobHandler(importJobService)
+	directoryHandler := handler.NewDirectoryHandler(directoryService)
+	eventCloneHandler := handler.NewEventCloneHandler(eventCloneService)
+	archiveHandler := handler.NewArchiveHandler(archiveService)
 
 	// Router
 	r := gin.Default()
@@ -98,6 +157,16 @@ func main() {
 			admin.POST("/events/:eventId/open", eventHandler.Open)
 			admin.POST("/events/:eventId/close", eventHandler.Close)
 			admin.POST("/events/:eventId/lock", eventHandler.Lock)
+			admin.POST("/events/:eventId/clone", eventCloneHandler.Clone)
+			admin.POST("/events/:eventId/archive", archiveHandler.Archive)
+			admin.DELETE("/events/:eventId", archiveHandler.Delete)
+
+			// Event templates
+			admin.POST("/events/:eventId/templates", eventCloneHandler.SaveTemplate)
+			admin.GET("/templates", eventCloneHandler.ListTemplates)
+			admin.GET("/templates/:templateId", eventCloneHandler.GetTemplate)
+			admin.DELETE("/templates/:templateId", eventCloneHandler.DeleteTemplate)
+			admin.POST("/templates/:templateId/events", eventCloneHandler.CreateFromTemplate)
 
 			// Slates
 			admin.POST("/events/:eventId/slates", slateHandler.Create)
@@ -112,17 +181,60 @@ func main() {
 
 			// Voters
 			admin.POST("/events/:eventId/voters/import", voterHandler.Import)
+			admin.POST("/events/:eventId/voters/import/jobs", importJobHandler.Create)
+			admin.GET("/events/:eventId/voters/import/jobs", importJobHandler.List)
+			admin.GET("/events/:eventId/voters/import/jobs/:jobId", importJobHandler.Get)
+			admin.POST("/events/:eventId/voters/from-directory", directoryHandler.PullIntoEvent)
+			admin.POST("/events/:eventId/voters", voterHandler.Create)
 			admin.GET("/events/:eventId/voters", voterHandler.List)
+			admin.PATCH("/events/:eventId/voters/:voterId", voterHandler.Update)
+			admin.DELETE("/events/:eventId/voters/:voterId", voterHandler.Delete)
+			admin.POST("/events/:eventId/voters/:voterId/enable", voterHandler.Enable)
+			admin.POST("/events/:eventId/voters/:voterId/disable", voterHandler.Disable)
 			admin.POST("/events/:eventId/voters/tokens/generate", voterHandler.GenerateTokens)
 			admin.GET("/events/:eventId/voters/tokens/export", voterHandler.ExportTokens)
+			admin.GET("/events/:eventId/voters/tokens/cards.pdf", voterHandler.TokenCards)
 			admin.GET("/events/:eventId/voters/turnout/export", voterHandler.ExportTurnout)
 			admin.GET("/voters/template", voterHandler.DownloadTemplate)
+			admin.GET("/events/:eventId/voters/:voterId/tokens", voterHandler.TokenHistory)
+			admin.POST("/events/:eventId/voters/:voterId/token/revoke", voterHandler.RevokeToken)
+			admin.POST("/events/:eventId/voters/:voterId/token/reissue", voterHandler.ReissueToken)
+
+			// Member directories
+			admin.POST("/directories", directoryHandler.Create)
+			admin.GET("/directories", directoryHandler.List)
+			admin.GET("/directories/:directoryId", directoryHandler.Get)
+			admin.PATCH("/directories/:directoryId", directoryHandler.Update)
+			admin.DELETE("/directories/:directoryId", directoryHandler.Delete)
+			admin.GET("/directories/:directoryId/members", directoryHandler.ListMembers)
+			admin.POST("/directories/:directoryId/members/import", directoryHandler.Import)
+
+			// Voter self-registration review
+			admin.GET("/events/:eventId/registrations", registrationHandler.List)
+			admin.POST("/events/:eventId/registrations/approve", registrationHandler.Approve)
+			admin.POST("/events/:eventId/registrations/reject", registrationHandler.Reject)
+
+			// Proxy voting
+			admin.POST("/events/:eventId/proxies", proxyHandler.Create)
+			admin.GET("/events/:eventId/proxies", proxyHandler.List)
+			admin.POST("/events/:eventId/proxies/:proxyId/revoke", proxyHandler.Revoke)
+
+			// Token delivery
+			admin.POST("/events/:eventId/voters/tokens/send", deliveryHandler.Send)
+			admin.POST("/events/:eventId/voters/tokens/deliveries/retry", deliveryHandler.Retry)
+			admin.GET("/events/:eventId/voters/tokens/deliveries", deliveryHandler.List)
+
+			// Voting lockouts
+			admin.GET("/events/:eventId/lockouts", lockoutHandler.List)
+			admin.DELETE("/events/:eventId/lockouts/:lockoutId", lockoutHandler.Clear)
 
 			// Stats
 			admin.GET("/events/:eventId/stats", statsHandler.GetStats)
+			admin.GET("/events/:eventId/stats/not-voted", statsHandler.ListNotVoted)
 
 			// Audit Logs
 			admin.GET("/events/:eventId/audit-logs", auditLogHandler.List)
+			admin.GET("/account/audit-logs", auditLogHandler.ListAccount)
 
 			// Payment
 			admin.POST("/events/:eventId/upgrade", paymentHandler.Upgrade)
@@ -138,7 +250,11 @@ func main() {
 			voteLimiter := middleware.NewRateLimiter(1000, 2) // 1000 req / 2 min per IP
 			public.GET("/events/:eventId", eventHandler.GetPublic)
 			public.POST("/events/:eventId/vote/prepare", voteLimiter.Middleware(), votePublicHandler.Prepare)
-			public.POST("/events/:eventId/vote/submit", votePublicHandler.Submit)
+			public.POST("/events/:eventId/vote/link", voteLimiter.Middleware(), votePublicHandler.PrepareLink)
+			public.POST("/events/:eventId/vote/submit", voteLimiter.Middleware(), votePublicHandler.Submit)
+
+			registerLimiter := middleware.NewRateLimiter(2, 20) // 20 req / 10 min per IP
+			public.POST("/events/:eventId/register", registerLimiter.Middleware(), registrationHandler.Register)
 		}
 
 		// Payment webhook (no auth, verified by signature)
@@ -0,0 +1,116 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+// HTTPGateway posts text messages to a generic WhatsApp/SMS gateway. The
+// request body is {"channel","to","message"} and the token, if set, is sent as
+// a bearer token. 4xx replies (except 429) are treated as bounces.
+type HTTPGateway struct {
+	channel model.DeliveryChannel
+	url     string
+	token   string
+	client  *http.Client
+}
+
+func NewHTTPGateway(channel model.DeliveryChannel, url, token string) *HTTPGateway {
+	return &HTTPGateway{
+		channel: channel,
+		url:     url,
+		token:   token,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *HTTPGateway) Channel() model.DeliveryChannel {
+	return g.channel
+}
+
+func (g *HTTPGateway) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"channel": string(g.channel),
+		"to":      msg.To,
+		"message": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("gateway returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", ErrBounced, err)
+	}
+	return err
+}
+
+// FileGateway appends every message as a JSON line to a local file instead of
+// sending it. Useful for development and tests.
+type FileGateway struct {
+	channel model.DeliveryChannel
+	path    string
+	mu      sync.Mutex
+}
+
+func NewFileGateway(channel model.DeliveryChannel, path string) *FileGateway {
+	return &FileGateway{channel: channel, path: path}
+}
+
+func (g *FileGateway) Channel() model.DeliveryChannel {
+	return g.channel
+}
+
+func (g *FileGateway) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"channel": g.channel,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"message": msg.Body,
+		"sent_at": time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f, err := os.OpenFile(g.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,32 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+// ErrBounced marks a permanent rejection by the recipient's side (unknown
+// mailbox, invalid number, …). Bounced deliveries are not retried.
+var ErrBounced = errors.New("recipient rejected the message")
+
+// Message is a single rendered token notification.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Provider sends messages over one delivery channel.
+type Provider interface {
+	Channel() model.DeliveryChannel
+	Send(ctx context.Context, msg Message) error
+}
+
+// Route binds a provider to the template and send rate used for its channel.
+type Route struct {
+	Provider      Provider
+	Template      *Template
+	RatePerMinute int
+}
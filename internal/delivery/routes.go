@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"fmt"
+
+	"github.com/amard/pemilo-golang/internal/config"
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+// RoutesFromConfig builds the delivery routes enabled by the configuration.
+// Email is always available. WhatsApp and SMS use their HTTP gateway when a
+// URL is set, otherwise the file-logging gateway when GATEWAY_LOG_FILE is set.
+func RoutesFromConfig(cfg *config.Config) ([]Route, error) {
+	emailTmpl, err := ParseTemplate(
+		orDefault(cfg.EmailSubjectTemplate, DefaultEmailSubject),
+		orDefault(cfg.EmailBodyTemplate, DefaultEmailBody),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("email template: %w", err)
+	}
+
+	routes := []Route{{
+		Provider:      NewSMTPProvider(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom),
+		Template:      emailTmpl,
+		RatePerMinute: cfg.EmailRatePerMinute,
+	}}
+
+	gateways := []struct {
+		channel  model.DeliveryChannel
+		url      string
+		token    string
+		template string
+		rate     int
+	}{
+		{model.DeliveryChannelWhatsApp, cfg.WhatsAppGatewayURL, cfg.WhatsAppGatewayToken, cfg.WhatsAppTemplate, cfg.WhatsAppRatePerMinute},
+		{model.DeliveryChannelSMS, cfg.SMSGatewayURL, cfg.SMSGatewayToken, cfg.SMSTemplate, cfg.SMSRatePerMinute},
+	}
+
+	for _, g := range gateways {
+		var provider Provider
+		switch {
+		case g.url != "":
+			provider = NewHTTPGateway(g.channel, g.url, g.token)
+		case cfg.GatewayLogFile != "":
+			provider = NewFileGateway(g.channel, cfg.GatewayLogFile)
+		default:
+			continue
+		}
+
+		tmpl, err := ParseTemplate("", orDefault(g.template, DefaultTextBody))
+		if err != nil {
+			return nil, fmt.Errorf("%s template: %w", g.channel, err)
+		}
+		routes = append(routes, Route{Provider: provider, Template: tmpl, RatePerMinute: g.rate})
+	}
+
+	return routes, nil
+}
+
+func orDefault(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
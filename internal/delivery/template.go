@@ -0,0 +1,67 @@
+package delivery
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Default templates. Placeholders: {{.FullName}} {{.NIM}} {{.Token}}
+// {{.EventTitle}} {{.VotingURL}}.
+const (
+	DefaultEmailSubject = `Your voting token for {{.EventTitle}}`
+	DefaultEmailBody    = `Hello {{.FullName}},
+
+You are registered to vote in "{{.EventTitle}}".
+
+NIM:   {{.NIM}}
+Token: {{.Token}}
+
+Vote here: {{.VotingURL}}
+
+Keep this token private. It can only be used once.
+`
+	DefaultTextBody = `Halo {{.FullName}}, token pemilihan "{{.EventTitle}}" Anda: {{.Token}} (NIM {{.NIM}}). Pilih di {{.VotingURL}} — jangan bagikan token ini.`
+)
+
+// TemplateData is the set of values available to message templates.
+type TemplateData struct {
+	FullName   string
+	NIM        string
+	Token      string
+	EventTitle string
+	VotingURL  string
+}
+
+// Template renders the subject and body of a token notification.
+type Template struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// ParseTemplate compiles a subject/body pair. Literal "\n" sequences are
+// turned into newlines so templates can be supplied through env vars.
+// Channels without a subject line may pass an empty subject.
+func ParseTemplate(subject, body string) (*Template, error) {
+	subj, err := template.New("subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("parse subject template: %w", err)
+	}
+	b, err := template.New("body").Option("missingkey=error").Parse(strings.ReplaceAll(body, `\n`, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("parse body template: %w", err)
+	}
+	return &Template{subject: subj, body: b}, nil
+}
+
+// Render executes the template for one recipient.
+func (t *Template) Render(to string, data TemplateData) (Message, error) {
+	var subj, body strings.Builder
+	if err := t.subject.Execute(&subj, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: subj.String(), Body: body.String()}, nil
+}
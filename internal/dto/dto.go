@@ -29,17 +29,50 @@ type UserDTO struct {
 // ── Event ──
 
 type CreateEventRequest struct {
-	Title       string     `json:"title" binding:"required"`
-	Description *string    `json:"description"`
-	OpensAt     *time.Time `json:"opens_at"`
-	ClosesAt    *time.Time `json:"closes_at"`
+	Title       string              `json:"title" binding:"required"`
+	Description *string             `json:"description"`
+	OpensAt     *time.Time          `json:"opens_at"`
+	ClosesAt    *time.Time          `json:"closes_at"`
+	TokenPolicy *TokenPolicyRequest `json:"token_policy"`
+	NIMPolicy   *NIMPolicyRequest   `json:"nim_policy"`
+	// SecondFactor is NONE, DOB or PHONE_LAST4.
+	SecondFactor *string `json:"second_factor" binding:"omitempty,oneof=NONE DOB PHONE_LAST4"`
 }
 
 type UpdateEventRequest struct {
-	Title       *string    `json:"title"`
-	Description *string    `json:"description"`
-	OpensAt     *time.Time `json:"opens_at"`
-	ClosesAt    *time.Time `json:"closes_at"`
+	Title       *string             `json:"title"`
+	Description *string             `json:"description"`
+	OpensAt     *time.Time          `json:"opens_at"`
+	ClosesAt    *time.Time          `json:"closes_at"`
+	TokenPolicy *TokenPolicyRequest `json:"token_policy"`
+	NIMPolicy   *NIMPolicyRequest   `json:"nim_policy"`
+	// RegistrationOpen toggles public voter self-registration.
+	RegistrationOpen *bool   `json:"registration_open"`
+	SecondFactor     *string `json:"second_factor" binding:"omitempty,oneof=NONE DOB PHONE_LAST4"`
+	// ProxyCap is how many members one voter may represent; 0 disables proxies.
+	ProxyCap *int `json:"proxy_cap" binding:"omitempty,min=0,max=20"`
+	// VoterAttributes replaces the custom voter attribute keys, e.g.
+	// ["faculty","cohort","campus"]. Values of removed keys stay on file.
+	VoterAttributes *[]string `json:"voter_attributes" binding:"omitempty,max=10"`
+}
+
+// TokenPolicyRequest sets an event's token format. Length counts random
+// characters; an empty alphabet means the ambiguity-free default.
+type TokenPolicyRequest struct {
+	Length    int    `json:"length" binding:"required"`
+	Alphabet  string `json:"alphabet"`
+	CheckChar bool   `json:"check_char"`
+}
+
+// NIMPolicyRequest sets how an event normalizes NIMs: whitespace and
+// strip_chars are removed, then optionally upper-cased and stripped of leading
+// zeros; a non-empty pattern is a regular expression the whole result must
+// match, e.g. "[A-Z][0-9]{9}".
+type NIMPolicyRequest struct {
+	StripChars       string `json:"strip_chars"`
+	CaseFold         bool   `json:"case_fold"`
+	TrimLeadingZeros bool   `json:"trim_leading_zeros"`
+	Pattern          string `json:"pattern"`
 }
 
 type EventPublicInfo struct {
@@ -49,6 +82,32 @@ type EventPublicInfo struct {
 	Status      string     `json:"status"`
 	OpensAt     *time.Time `json:"opens_at"`
 	ClosesAt    *time.Time `json:"closes_at"`
+	TokenLength int        `json:"token_length"`
+
+	RegistrationOpen bool    `json:"registration_open"`
+	SecondFactor     *string `json:"second_factor"`
+}
+
+// ── Event Cloning & Templates ──
+
+// CloneEventRequest picks what a clone copies besides the title and
+// description. Tokens, ballots, status and the schedule are never copied.
+type CloneEventRequest struct {
+	// Title defaults to the source title with " (copy)" appended.
+	Title        *string `json:"title"`
+	CopySlates   bool    `json:"copy_slates"`
+	CopyVoters   bool    `json:"copy_voters"`
+	CopySettings bool    `json:"copy_settings"`
+}
+
+type CreateTemplateRequest struct {
+	Name          string `json:"name" binding:"required"`
+	IncludeSlates bool   `json:"include_slates"`
+}
+
+type CreateFromTemplateRequest struct {
+	// Title defaults to the template's event title.
+	Title *string `json:"title"`
 }
 
 // ── Slate ──
@@ -92,6 +151,8 @@ type UpdateSlateMemberRequest struct {
 type VotePrepareRequest struct {
 	Token string `json:"token" binding:"required"`
 	NIM   string `json:"nim" binding:"required"`
+	// SecondFactor is required when the event has one configured.
+	SecondFactor string `json:"second_factor"`
 }
 
 type VotePrepareResponse struct {
@@ -99,6 +160,17 @@ type VotePrepareResponse struct {
 	VoterDisplay VoterDisplay  `json:"voter_display"`
 	Slates       []SlatePublic `json:"slates"`
 	ExpiresAt    time.Time     `json:"expires_at"`
+	// HasVoted is set when the voter's own ballot is already cast and the
+	// session only offers proxy ballots.
+	HasVoted bool          `json:"has_voted"`
+	Proxies  []ProxyBallot `json:"proxies"`
+}
+
+// ProxyBallot is a member whose ballot the voter may cast as proxy holder.
+type ProxyBallot struct {
+	ProxyID   string  `json:"proxy_id"`
+	FullName  string  `json:"full_name"`
+	ClassName *string `json:"class_name"`
 }
 
 type VoterDisplay struct {
@@ -124,10 +196,26 @@ type SlateMemberPublic struct {
 	SortOrder int     `json:"sort_order"`
 }
 
+// VoteLinkRequest opens a voting session from a per-voter magic link.
+type VoteLinkRequest struct {
+	Link string `json:"link" binding:"required"`
+	// SecondFactor is required when the event has one configured, as with
+	// token+NIM.
+	SecondFactor string `json:"second_factor"`
+}
+
+// VoteSubmitRequest identifies the voter either by token+NIM or by the magic
+// link credential returned from the link endpoint.
 type VoteSubmitRequest struct {
-	Token   string `json:"token" binding:"required"`
-	NIM     string `json:"nim" binding:"required"`
+	Token   string `json:"token" binding:"required_without=Link"`
+	NIM     string `json:"nim" binding:"required_without=Link"`
+	Link    string `json:"link"`
 	SlateID string `json:"slate_id" binding:"required,uuid"`
+
+	SecondFactor string `json:"second_factor"`
+	// ProxyID casts the ballot of the member who delegated to this voter
+	// instead of the voter's own.
+	ProxyID string `json:"proxy_id" binding:"omitempty,uuid"`
 }
 
 // ── Stats ──
@@ -139,7 +227,43 @@ type StatsResponse struct {
 	NotVotedCount int           `json:"not_voted_count"`
 	VotesBySlate  []SlateVotes  `json:"votes_by_slate"`
 	LatestVoters  []LatestVoter `json:"latest_voters"`
-	UpdatedAt     time.Time     `json:"updated_at"`
+	// TurnoutByClass breaks turnout down by class_name; voters without a
+	// class are grouped under "".
+	TurnoutByClass []ClassTurnout `json:"turnout_by_class"`
+	// TurnoutByAttribute breaks turnout down by each custom voter
+	// attribute. It never includes per-slate votes.
+	TurnoutByAttribute map[string][]TurnoutSegment `json:"turnout_by_attribute"`
+	UpdatedAt          time.Time                   `json:"updated_at"`
+}
+
+type ClassTurnout struct {
+	ClassName      string  `json:"class_name"`
+	TotalVoters    int     `json:"total_voters"`
+	VotedCount     int     `json:"voted_count"`
+	TurnoutPercent float64 `json:"turnout_percent"`
+}
+
+// NotVotedVoter is a voter who has not voted yet, for class coordinators.
+type NotVotedVoter struct {
+	ID        string  `json:"id"`
+	FullName  string  `json:"full_name"`
+	NIMRaw    string  `json:"nim_raw"`
+	ClassName *string `json:"class_name"`
+	Status    string  `json:"status"`
+}
+
+type NotVotedListResponse struct {
+	Voters  []NotVotedVoter `json:"voters"`
+	Total   int             `json:"total"`
+	Page    int             `json:"page"`
+	PerPage int             `json:"per_page"`
+}
+
+// TurnoutSegment is the turnout of the voters sharing one attribute value.
+type TurnoutSegment struct {
+	Value       string `json:"value"`
+	TotalVoters int    `json:"total_voters"`
+	VotedCount  int    `json:"voted_count"`
 }
 
 type SlateVotes struct {
@@ -167,32 +291,229 @@ type ImportReject struct {
 	Reason string `json:"reason"`
 }
 
+// ImportSyncResult reports what a sync import did. Voters who have voted are
+// counted in SkippedVotedCount and never changed.
+type ImportSyncResult struct {
+	AddedCount        int            `json:"added_count"`
+	UpdatedCount      int            `json:"updated_count"`
+	UnchangedCount    int            `json:"unchanged_count"`
+	DisabledCount     int            `json:"disabled_count"`
+	SkippedVotedCount int            `json:"skipped_voted_count"`
+	Rejected          []ImportReject `json:"rejected"`
+}
+
+// ── Member Directories ──
+
+type DirectoryRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// DirectoryImportResult reports what a directory import did. Without sync,
+// NIMs already in the directory are in Rejected.
+type DirectoryImportResult struct {
+	AddedCount       int            `json:"added_count"`
+	UpdatedCount     int            `json:"updated_count"`
+	UnchangedCount   int            `json:"unchanged_count"`
+	DeactivatedCount int            `json:"deactivated_count"`
+	Rejected         []ImportReject `json:"rejected"`
+}
+
+// PullDirectoryRequest selects the active directory members to add to an
+// event. An empty filter takes every active member.
+type PullDirectoryRequest struct {
+	DirectoryID string            `json:"directory_id" binding:"required"`
+	ClassNames  []string          `json:"class_names"`
+	Attributes  map[string]string `json:"attributes"`
+}
+
+type DirectoryPullResult struct {
+	AddedCount int `json:"added_count"`
+	// AlreadyPresentCount counts members whose NIM is already on the roster.
+	AlreadyPresentCount int                   `json:"already_present_count"`
+	Rejected            []DirectoryPullReject `json:"rejected"`
+}
+
+type DirectoryPullReject struct {
+	MemberID string `json:"member_id"`
+	NIMRaw   string `json:"nim_raw"`
+	FullName string `json:"full_name"`
+	Reason   string `json:"reason"`
+}
+
+// ImportPreview is the dry-run result of an import; nothing is written.
+type ImportPreview struct {
+	// Mode is "insert" or "sync", the import the preview is for.
+	Mode      string             `json:"mode"`
+	NewVoters []ImportPreviewRow `json:"new_voters"`
+	// Changed lists NIMs already on the roster whose name or class in the
+	// file differs. An insert rejects every existing NIM, so there they are
+	// also in Rejected; a sync updates them.
+	Changed        []ImportPreviewChange `json:"changed"`
+	UnchangedCount int                   `json:"unchanged_count"`
+	// SkippedVotedCount counts existing voters a sync leaves alone because
+	// they have voted; DisabledCount the voters disable_missing would disable.
+	SkippedVotedCount int            `json:"skipped_voted_count"`
+	DisabledCount     int            `json:"disabled_count"`
+	Rejected          []ImportReject `json:"rejected"`
+	CurrentCount      int            `json:"current_count"`
+	MaxVoters         int            `json:"max_voters"`
+	ExceedsLimit      bool           `json:"exceeds_limit"`
+}
+
+type ImportPreviewRow struct {
+	Row       int    `json:"row"`
+	FullName  string `json:"full_name"`
+	NIMRaw    string `json:"nim_raw"`
+	ClassName string `json:"class_name"`
+}
+
+type ImportPreviewChange struct {
+	Row          int    `json:"row"`
+	VoterID      string `json:"voter_id"`
+	NIMRaw       string `json:"nim_raw"`
+	FullName     string `json:"full_name"`
+	ClassName    string `json:"class_name"`
+	NewFullName  string `json:"new_full_name"`
+	NewClassName string `json:"new_class_name"`
+
+	Attributes    map[string]string `json:"attributes"`
+	NewAttributes map[string]string `json:"new_attributes"`
+}
+
+// ── Voter Registration ──
+
+type VoterRegistrationRequest struct {
+	FullName  string `json:"full_name" binding:"required"`
+	NIM       string `json:"nim" binding:"required"`
+	ClassName string `json:"class_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	DOB       string `json:"dob"`
+}
+
+type ApproveRegistrationsRequest struct {
+	IDs         []string `json:"ids" binding:"required,min=1,dive,uuid"`
+	IssueTokens bool     `json:"issue_tokens"`
+}
+
+type RejectRegistrationsRequest struct {
+	IDs    []string `json:"ids" binding:"required,min=1,dive,uuid"`
+	Reason string   `json:"reason"`
+}
+
+type RegistrationReviewResult struct {
+	ReviewedCount int                `json:"reviewed_count"`
+	TokensIssued  int                `json:"tokens_issued"`
+	Skipped       []RegistrationSkip `json:"skipped"`
+}
+
+type RegistrationSkip struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// ── Proxy Voting ──
+
+type CreateProxyRequest struct {
+	GrantorVoterID string `json:"grantor_voter_id" binding:"required,uuid"`
+	HolderVoterID  string `json:"holder_voter_id" binding:"required,uuid"`
+	Note           string `json:"note"`
+}
+
 // ── Voter List ──
 
 type VoterListParams struct {
 	Query    string
 	Status   string
 	HasVoted *bool
-	Page     int
-	PerPage  int
+	// Attributes filters on exact custom attribute values.
+	Attributes map[string]string
+	// Sort is one of created_at, full_name, nim, class_name or voted_at.
+	Sort string
+	Desc bool
+	// Cursor continues after the last voter of the previous page; when set,
+	// Page is ignored.
+	Cursor    string
+	After     *VoterCursor
+	WithTotal bool
+	Page      int
+	PerPage   int
+}
+
+// VoterCursor is a decoded list cursor: the sort key and id of the last voter
+// on the previous page.
+type VoterCursor struct {
+	Value string
+	ID    string
 }
 
 type VoterListResponse struct {
-	Voters  []VoterDTO `json:"voters"`
-	Total   int        `json:"total"`
-	Page    int        `json:"page"`
-	PerPage int        `json:"per_page"`
+	Voters []VoterDTO `json:"voters"`
+	// Total is null unless requested with include_total.
+	Total      *int    `json:"total"`
+	NextCursor *string `json:"next_cursor"`
+	Page       int     `json:"page"`
+	PerPage    int     `json:"per_page"`
 }
 
 type VoterDTO struct {
-	ID        string     `json:"id"`
-	FullName  string     `json:"full_name"`
-	NIMRaw    string     `json:"nim_raw"`
-	ClassName *string    `json:"class_name"`
-	HasVoted  bool       `json:"has_voted"`
-	VotedAt   *time.Time `json:"voted_at"`
-	Status    string     `json:"status"`
-	Token     *string    `json:"token,omitempty"`
+	ID         string            `json:"id"`
+	FullName   string            `json:"full_name"`
+	NIMRaw     string            `json:"nim_raw"`
+	ClassName  *string           `json:"class_name"`
+	Email      *string           `json:"email"`
+	Phone      *string           `json:"phone"`
+	HasVoted   bool              `json:"has_voted"`
+	VotedAt    *time.Time        `json:"voted_at"`
+	Status     string            `json:"status"`
+	Token      *string           `json:"token,omitempty"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ── Voter CRUD ──
+
+type CreateVoterRequest struct {
+	FullName  string `json:"full_name" binding:"required"`
+	NIM       string `json:"nim" binding:"required"`
+	ClassName string `json:"class_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	DOB       string `json:"dob"`
+	// Attributes sets values for the event's custom voter attributes.
+	Attributes map[string]string `json:"attributes"`
+}
+
+// UpdateVoterRequest changes only the fields that are present. An empty
+// class_name, email or phone clears it; dob can be replaced but not cleared.
+// attributes, when present, replaces all custom attribute values.
+type UpdateVoterRequest struct {
+	FullName  *string `json:"full_name"`
+	NIM       *string `json:"nim"`
+	ClassName *string `json:"class_name"`
+	Email     *string `json:"email"`
+	Phone     *string `json:"phone"`
+	DOB       *string `json:"dob"`
+
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ── Token Revocation ──
+
+type TokenActionRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ── Token Delivery ──
+
+type SendTokensRequest struct {
+	Channel string `json:"channel" binding:"required,oneof=EMAIL WHATSAPP SMS"`
+	// Mode is "unsent" (default) to skip voters who already received their
+	// token on this channel, or "all" to send to every voter again.
+	Mode string `json:"mode" binding:"omitempty,oneof=all unsent"`
+}
+
+type RetryDeliveriesRequest struct {
+	Channel string `json:"channel" binding:"required,oneof=EMAIL WHATSAPP SMS"`
 }
 
 // ── Payment ──
@@ -246,3 +567,21 @@ type AuditLogListResponse struct {
 	Page    int           `json:"page"`
 	PerPage int           `json:"per_page"`
 }
+
+// AccountAuditLogDTO is an entry in the account-level audit trail; EventID may
+// name a deleted event.
+type AccountAuditLogDTO struct {
+	ID          string    `json:"id"`
+	Action      string    `json:"action"`
+	ActorUserID *string   `json:"actor_user_id"`
+	EventID     *string   `json:"event_id"`
+	Meta        string    `json:"meta"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AccountAuditLogListResponse struct {
+	Logs    []AccountAuditLogDTO `json:"logs"`
+	Total   int                  `json:"total"`
+	Page    int                  `json:"page"`
+	PerPage int                  `json:"per_page"`
+}
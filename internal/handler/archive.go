@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveHandler struct {
+	archiveService *service.ArchiveService
+}
+
+func NewArchiveHandler(archiveService *service.ArchiveService) *ArchiveHandler {
+	return &ArchiveHandler{archiveService: archiveService}
+}
+
+// POST /api/events/:eventId/archive
+func (h *ArchiveHandler) Archive(c *gin.Context) {
+	if err := h.archiveService.Archive(c.Request.Context(), c.Param("eventId"), middleware.GetUserID(c)); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapArchiveError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "event archived"})
+}
+
+// DELETE /api/events/:eventId
+func (h *ArchiveHandler) Delete(c *gin.Context) {
+	if err := h.archiveService.Delete(c.Request.Context(), c.Param("eventId"), middleware.GetUserID(c)); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapArchiveError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "event deleted"})
+}
+
+func mapArchiveError(err error) int {
+	switch err {
+	case service.ErrEventNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrEventNotLocked, service.ErrEventArchived, service.ErrEventNotDraft, service.ErrEventHasPayments:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type DeliveryHandler struct {
+	deliveryService *service.DeliveryService
+}
+
+func NewDeliveryHandler(deliveryService *service.DeliveryService) *DeliveryHandler {
+	return &DeliveryHandler{deliveryService: deliveryService}
+}
+
+// POST /api/events/:eventId/voters/tokens/send
+func (h *DeliveryHandler) Send(c *gin.Context) {
+	var req dto.SendTokensRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	resend := req.Mode == "all"
+	queued, err := h.deliveryService.SendTokens(c.Request.Context(), eventID, userID, model.DeliveryChannel(req.Channel), resend)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDeliveryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.SuccessResponse{OK: true, Data: gin.H{"queued_count": queued}})
+}
+
+// POST /api/events/:eventId/voters/tokens/deliveries/retry
+func (h *DeliveryHandler) Retry(c *gin.Context) {
+	var req dto.RetryDeliveriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	requeued, err := h.deliveryService.Retry(c.Request.Context(), eventID, userID, model.DeliveryChannel(req.Channel))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDeliveryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.SuccessResponse{OK: true, Data: gin.H{"requeued_count": requeued}})
+}
+
+// GET /api/events/:eventId/voters/tokens/deliveries
+func (h *DeliveryHandler) List(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	deliveries, err := h.deliveryService.List(c.Request.Context(), eventID, userID, c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDeliveryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: deliveries})
+}
+
+func mapDeliveryError(err error) int {
+	switch err {
+	case service.ErrEventNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrEventLocked:
+		return http.StatusConflict
+	case service.ErrChannelNotConfigured:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
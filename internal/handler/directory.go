@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type DirectoryHandler struct {
+	directoryService *service.DirectoryService
+}
+
+func NewDirectoryHandler(directoryService *service.DirectoryService) *DirectoryHandler {
+	return &DirectoryHandler{directoryService: directoryService}
+}
+
+// POST /api/directories
+func (h *DirectoryHandler) Create(c *gin.Context) {
+	var req dto.DirectoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	directory, err := h.directoryService.Create(c.Request.Context(), middleware.GetUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: directory})
+}
+
+// GET /api/directories
+func (h *DirectoryHandler) List(c *gin.Context) {
+	directories, err := h.directoryService.List(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{OK: false, Error: "failed to list directories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: directories})
+}
+
+// GET /api/directories/:directoryId
+func (h *DirectoryHandler) Get(c *gin.Context) {
+	directory, err := h.directoryService.Get(c.Request.Context(), c.Param("directoryId"), middleware.GetUserID(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: directory})
+}
+
+// PATCH /api/directories/:directoryId
+func (h *DirectoryHandler) Update(c *gin.Context) {
+	var req dto.DirectoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	directory, err := h.directoryService.Rename(c.Request.Context(), c.Param("directoryId"), middleware.GetUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: directory})
+}
+
+// DELETE /api/directories/:directoryId
+func (h *DirectoryHandler) Delete(c *gin.Context) {
+	if err := h.directoryService.Delete(c.Request.Context(), c.Param("directoryId"), middleware.GetUserID(c)); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "directory deleted"})
+}
+
+// GET /api/directories/:directoryId/members
+// Filters: q, class_name, active=true|false and attr.<key>=<value>.
+func (h *DirectoryHandler) ListMembers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	filter := repository.DirectoryMemberFilter{Query: c.Query("q")}
+	if className := c.Query("class_name"); className != "" {
+		filter.ClassNames = []string{className}
+	}
+	if a := c.Query("active"); a != "" {
+		v := a == "true"
+		filter.Active = &v
+	}
+	for name, values := range c.Request.URL.Query() {
+		if key := strings.TrimPrefix(name, "attr."); key != name && key != "" && len(values) > 0 {
+			if filter.Attributes == nil {
+				filter.Attributes = make(map[string]string)
+			}
+			filter.Attributes[key] = values[0]
+		}
+	}
+
+	members, total, err := h.directoryService.ListMembers(c.Request.Context(), c.Param("directoryId"), middleware.GetUserID(c), filter, page, perPage)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: gin.H{
+		"members":  members,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	}})
+}
+
+// POST /api/directories/:directoryId/members/import
+// Same upload as the voter import. ?mode=sync updates existing members, and
+// with &deactivate_missing=true deactivates members absent from the file.
+func (h *DirectoryHandler) Import(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "file is required"})
+		return
+	}
+	defer file.Close()
+
+	upload := service.RosterUpload{File: file}
+	if isXLSX(header.Filename, file) {
+		upload.XLSX = true
+		upload.Sheet = c.DefaultPostForm("sheet", c.Query("sheet"))
+	}
+
+	sync := c.Query("mode") == "sync"
+	result, err := h.directoryService.Import(c.Request.Context(), c.Param("directoryId"), middleware.GetUserID(c), upload,
+		sync, sync && c.Query("deactivate_missing") == "true")
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: result})
+}
+
+// POST /api/events/:eventId/voters/from-directory
+func (h *DirectoryHandler) PullIntoEvent(c *gin.Context) {
+	var req dto.PullDirectoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	result, err := h.directoryService.PullIntoEvent(c.Request.Context(), c.Param("eventId"), middleware.GetUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapDirectoryError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: result})
+}
+
+func mapDirectoryError(err error) int {
+	switch err {
+	case service.ErrDirectoryNotFound, service.ErrEventNotFound:
+		return http.StatusNotFound
+	case service.ErrDirectoryForbidden, service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrEventLocked, service.ErrDirectoryNoDOB:
+		return http.StatusConflict
+	case service.ErrDirectoryNameEmpty, service.ErrMaxVotersReached, service.ErrEmptyRoster:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/amard/pemilo-golang/internal/dto"
@@ -27,6 +28,10 @@ func (h *EventHandler) Create(c *gin.Context) {
 
 	userID := middleware.GetUserID(c)
 	event, err := h.eventService.Create(c.Request.Context(), userID, req)
+	if err == service.ErrInvalidTokenPolicy || err == service.ErrInvalidNIMPolicy {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
 	if err != nil {
 		_ = c.Error(err)
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{OK: false, Error: "failed to create event"})
@@ -146,6 +151,10 @@ func (h *EventHandler) Lock(c *gin.Context) {
 }
 
 func mapEventError(err error) int {
+	var missing *service.SecondFactorMissingError
+	if errors.As(err, &missing) {
+		return http.StatusConflict
+	}
 	switch err {
 	case service.ErrEventNotFound:
 		return http.StatusNotFound
@@ -153,8 +162,10 @@ func mapEventError(err error) int {
 		return http.StatusForbidden
 	case service.ErrEventLocked:
 		return http.StatusConflict
-	case service.ErrInvalidTransition:
+	case service.ErrInvalidTransition, service.ErrInvalidTokenPolicy, service.ErrInvalidNIMPolicy, service.ErrInvalidAttributes, service.ErrInvalidProxyCap:
 		return http.StatusBadRequest
+	case service.ErrTokenPolicyLocked, service.ErrSecondFactorLocked, service.ErrNIMPolicyLocked:
+		return http.StatusConflict
 	default:
 		return http.StatusInternalServerError
 	}
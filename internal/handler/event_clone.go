@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type EventCloneHandler struct {
+	cloneService *service.EventCloneService
+}
+
+func NewEventCloneHandler(cloneService *service.EventCloneService) *EventCloneHandler {
+	return &EventCloneHandler{cloneService: cloneService}
+}
+
+// POST /api/events/:eventId/clone
+// An empty body copies only the title and description.
+func (h *EventCloneHandler) Clone(c *gin.Context) {
+	var req dto.CloneEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	resp, err := h.cloneService.Clone(c.Request.Context(), c.Param("eventId"), middleware.GetUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapCloneError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: resp})
+}
+
+// POST /api/events/:eventId/templates
+func (h *EventCloneHandler) SaveTemplate(c *gin.Context) {
+	var req dto.CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	template, err := h.cloneService.SaveTemplate(c.Request.Context(), c.Param("eventId"), middleware.GetUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapCloneError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: template})
+}
+
+// GET /api/templates
+func (h *EventCloneHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.cloneService.ListTemplates(c.Request.Context(), middleware.GetUserID(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{OK: false, Error: "failed to list templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: templates})
+}
+
+// GET /api/templates/:templateId
+func (h *EventCloneHandler) GetTemplate(c *gin.Context) {
+	template, err := h.cloneService.GetTemplate(c.Request.Context(), c.Param("templateId"), middleware.GetUserID(c))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapCloneError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: template})
+}
+
+// DELETE /api/templates/:templateId
+func (h *EventCloneHandler) DeleteTemplate(c *gin.Context) {
+	if err := h.cloneService.DeleteTemplate(c.Request.Context(), c.Param("templateId"), middleware.GetUserID(c)); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapCloneError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "template deleted"})
+}
+
+// POST /api/templates/:templateId/events
+func (h *EventCloneHandler) CreateFromTemplate(c *gin.Context) {
+	var req dto.CreateFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	event, err := h.cloneService.CreateFromTemplate(c.Request.Context(), c.Param("templateId"), middleware.GetUserID(c), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapCloneError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: event})
+}
+
+func mapCloneError(err error) int {
+	var missing *service.SecondFactorMissingError
+	if errors.As(err, &missing) {
+		return http.StatusConflict
+	}
+	switch err {
+	case service.ErrEventNotFound, service.ErrTemplateNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrTemplateNameTaken, service.ErrCloneArchived:
+		return http.StatusConflict
+	case service.ErrTemplateNameEmpty, service.ErrMaxSlatesReached:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
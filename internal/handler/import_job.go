@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ImportJobHandler struct {
+	importJobService *service.ImportJobService
+}
+
+func NewImportJobHandler(importJobService *service.ImportJobService) *ImportJobHandler {
+	return &ImportJobHandler{importJobService: importJobService}
+}
+
+// POST /api/events/:eventId/voters/import/jobs
+// Takes the same upload as the synchronous import, including ?mode=sync and
+// ?disable_missing=true, and returns the queued job at once.
+func (h *ImportJobHandler) Create(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "file is required"})
+		return
+	}
+	defer file.Close()
+
+	upload := repository.ImportJobFile{Name: header.Filename}
+	if isXLSX(header.Filename, file) {
+		upload.XLSX = true
+		upload.Sheet = c.DefaultPostForm("sheet", c.Query("sheet"))
+	}
+	if upload.Data, err = io.ReadAll(file); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "failed to read file"})
+		return
+	}
+
+	mode := model.ImportModeInsert
+	if c.Query("mode") == "sync" {
+		mode = model.ImportModeSync
+	}
+
+	job, err := h.importJobService.Enqueue(c.Request.Context(), eventID, userID, mode, c.Query("disable_missing") == "true", upload)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapImportJobError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.SuccessResponse{OK: true, Data: job})
+}
+
+// GET /api/events/:eventId/voters/import/jobs
+func (h *ImportJobHandler) List(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	jobs, err := h.importJobService.List(c.Request.Context(), eventID, userID)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapImportJobError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: jobs})
+}
+
+// GET /api/events/:eventId/voters/import/jobs/:jobId
+func (h *ImportJobHandler) Get(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	job, err := h.importJobService.Get(c.Request.Context(), eventID, c.Param("jobId"), userID)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapImportJobError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: job})
+}
+
+func mapImportJobError(err error) int {
+	switch err {
+	case service.ErrEventNotFound, service.ErrImportJobNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrEventLocked:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type LockoutHandler struct {
+	lockoutService *service.LockoutService
+}
+
+func NewLockoutHandler(lockoutService *service.LockoutService) *LockoutHandler {
+	return &LockoutHandler{lockoutService: lockoutService}
+}
+
+// GET /api/events/:eventId/lockouts?active=true
+func (h *LockoutHandler) List(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	lockouts, err := h.lockoutService.List(c.Request.Context(), eventID, userID, c.Query("active") == "true")
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapLockoutError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: lockouts})
+}
+
+// DELETE /api/events/:eventId/lockouts/:lockoutId
+func (h *LockoutHandler) Clear(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	if err := h.lockoutService.Clear(c.Request.Context(), eventID, c.Param("lockoutId"), userID); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapLockoutError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "lockout cleared"})
+}
+
+func mapLockoutError(err error) int {
+	switch err {
+	case service.ErrEventNotFound, service.ErrLockoutNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -126,3 +126,18 @@ func (h *AuditLogHandler) List(c *gin.Context) {
 
 	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: resp})
 }
+
+// GET /api/account/audit-logs
+func (h *AuditLogHandler) ListAccount(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	resp, err := h.auditService.ListAccount(c.Request.Context(), middleware.GetUserID(c), page, perPage)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: resp})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ProxyHandler struct {
+	proxyService *service.ProxyService
+}
+
+func NewProxyHandler(proxyService *service.ProxyService) *ProxyHandler {
+	return &ProxyHandler{proxyService: proxyService}
+}
+
+// POST /api/events/:eventId/proxies
+func (h *ProxyHandler) Create(c *gin.Context) {
+	var req dto.CreateProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	proxy, err := h.proxyService.Create(c.Request.Context(), eventID, userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapProxyError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: proxy})
+}
+
+// GET /api/events/:eventId/proxies
+func (h *ProxyHandler) List(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	proxies, err := h.proxyService.List(c.Request.Context(), eventID, userID)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapProxyError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: proxies})
+}
+
+// POST /api/events/:eventId/proxies/:proxyId/revoke
+func (h *ProxyHandler) Revoke(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	if err := h.proxyService.Revoke(c.Request.Context(), eventID, c.Param("proxyId"), userID); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapProxyError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "proxy revoked"})
+}
+
+func mapProxyError(err error) int {
+	switch err {
+	case service.ErrEventNotFound, service.ErrVoterNotFound, service.ErrProxyNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrEventLocked, service.ErrProxyExists, service.ErrProxyChain, service.ErrProxyCapReached,
+		service.ErrProxyNotActive:
+		return http.StatusConflict
+	case service.ErrProxySelf, service.ErrProxyVoterIneligible, service.ErrProxiesDisabled:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
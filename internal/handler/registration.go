@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type RegistrationHandler struct {
+	registrationService *service.RegistrationService
+}
+
+func NewRegistrationHandler(registrationService *service.RegistrationService) *RegistrationHandler {
+	return &RegistrationHandler{registrationService: registrationService}
+}
+
+// POST /api/public/events/:eventId/register
+func (h *RegistrationHandler) Register(c *gin.Context) {
+	var req dto.VoterRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "full_name and nim are required"})
+		return
+	}
+
+	eventID := c.Param("eventId")
+
+	if _, err := h.registrationService.Register(c.Request.Context(), eventID, req); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapRegistrationError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Message: "registration received and awaiting approval"})
+}
+
+// GET /api/events/:eventId/registrations?status=PENDING
+func (h *RegistrationHandler) List(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	regs, err := h.registrationService.List(c.Request.Context(), eventID, userID, c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapRegistrationError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: regs})
+}
+
+// POST /api/events/:eventId/registrations/approve
+func (h *RegistrationHandler) Approve(c *gin.Context) {
+	var req dto.ApproveRegistrationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	result, err := h.registrationService.Approve(c.Request.Context(), eventID, userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapRegistrationError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: result})
+}
+
+// POST /api/events/:eventId/registrations/reject
+func (h *RegistrationHandler) Reject(c *gin.Context) {
+	var req dto.RejectRegistrationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	result, err := h.registrationService.Reject(c.Request.Context(), eventID, userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapRegistrationError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: result})
+}
+
+func mapRegistrationError(err error) int {
+	switch err {
+	case service.ErrEventNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden, service.ErrRegistrationClosed:
+		return http.StatusForbidden
+	case service.ErrEventLocked, service.ErrAlreadyRegistered:
+		return http.StatusConflict
+	case service.ErrInvalidNIM, service.ErrNIMFormat, service.ErrInvalidEmail, service.ErrInvalidPhone, service.ErrContactRequired,
+		service.ErrInvalidDOB, service.ErrDOBRequired, service.ErrPhoneRequired, service.ErrMaxVotersReached:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/amard/pemilo-golang/internal/dto"
 	"github.com/amard/pemilo-golang/internal/middleware"
@@ -38,3 +39,33 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// GET /api/events/:eventId/stats/not-voted?class_name=&page=&per_page=
+// Without class_name every class is listed; an empty class_name selects voters
+// without a class.
+func (h *StatsHandler) ListNotVoted(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	var className *string
+	if v, ok := c.GetQuery("class_name"); ok {
+		className = &v
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "50"))
+
+	result, err := h.statsService.ListNotVoted(c.Request.Context(), eventID, userID, className, page, perPage)
+	if err != nil {
+		_ = c.Error(err)
+		status := http.StatusInternalServerError
+		if err == service.ErrEventNotFound {
+			status = http.StatusNotFound
+		} else if err == service.ErrEventForbidden {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: result})
+}
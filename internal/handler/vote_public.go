@@ -32,6 +32,35 @@ func (h *VotePublicHandler) Prepare(c *gin.Context) {
 		status := mapVoteError(err)
 		// Use generic error message — don't leak info
 		msg := "invalid token or NIM"
+		if err == service.ErrEventNotOpen {
+			msg = "voting is not open"
+		} else if err == service.ErrAlreadyVoted {
+			msg = "you have already voted"
+		} else if err == service.ErrTooManyAttempts {
+			msg = err.Error()
+		}
+		c.JSON(status, dto.ErrorResponse{OK: false, Error: msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/public/events/:eventId/vote/link
+func (h *VotePublicHandler) PrepareLink(c *gin.Context) {
+	var req dto.VoteLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "link is required"})
+		return
+	}
+
+	eventID := c.Param("eventId")
+
+	resp, err := h.voteService.PrepareLink(c.Request.Context(), eventID, req)
+	if err != nil {
+		_ = c.Error(err)
+		status := mapVoteError(err)
+		msg := "invalid or expired voting link"
 		if err == service.ErrEventNotOpen {
 			msg = "voting is not open"
 		} else if err == service.ErrAlreadyVoted {
@@ -48,7 +77,7 @@ func (h *VotePublicHandler) Prepare(c *gin.Context) {
 func (h *VotePublicHandler) Submit(c *gin.Context) {
 	var req dto.VoteSubmitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "token, nim, and slate_id are required"})
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "token and nim (or link), and slate_id are required"})
 		return
 	}
 
@@ -62,8 +91,12 @@ func (h *VotePublicHandler) Submit(c *gin.Context) {
 			msg = "voting is not open"
 		} else if err == service.ErrAlreadyVoted {
 			msg = "you have already voted"
+		} else if err == service.ErrTooManyAttempts {
+			msg = err.Error()
 		} else if err == service.ErrInvalidSlate {
 			msg = "invalid slate selection"
+		} else if err == service.ErrProxyNotFound || err == service.ErrProxyNotActive || err == service.ErrProxyGrantorVoted {
+			msg = err.Error()
 		}
 		c.JSON(status, dto.ErrorResponse{OK: false, Error: msg})
 		return
@@ -80,10 +113,14 @@ func mapVoteError(err error) int {
 		return http.StatusForbidden
 	case service.ErrInvalidToken, service.ErrVoterNotEligible:
 		return http.StatusUnauthorized
-	case service.ErrAlreadyVoted:
+	case service.ErrAlreadyVoted, service.ErrProxyNotActive, service.ErrProxyGrantorVoted:
 		return http.StatusConflict
-	case service.ErrInvalidSlate:
+	case service.ErrProxyNotFound:
+		return http.StatusNotFound
+	case service.ErrInvalidSlate, service.ErrNIMFormat:
 		return http.StatusBadRequest
+	case service.ErrTooManyAttempts:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -1,14 +1,20 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/amard/pemilo-golang/internal/dto"
 	"github.com/amard/pemilo-golang/internal/middleware"
+	"github.com/amard/pemilo-golang/internal/model"
 	"github.com/amard/pemilo-golang/internal/service"
+	"github.com/amard/pemilo-golang/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,18 +27,40 @@ func NewVoterHandler(voterService *service.VoterService) *VoterHandler {
 }
 
 // POST /api/events/:eventId/voters/import
+// Accepts a .csv or .xlsx file; for workbooks the optional "sheet" field picks
+// the sheet (default: the first one). With ?dry_run=true nothing is written
+// and a preview of the changes the chosen mode would make is returned instead.
+// With ?mode=sync existing NIMs are updated rather than rejected, and
+// ?disable_missing=true also disables voters absent from the file; voters who
+// have voted are untouched.
 func (h *VoterHandler) Import(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	eventID := c.Param("eventId")
 
-	file, _, err := c.Request.FormFile("file")
+	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "file is required"})
 		return
 	}
 	defer file.Close()
 
-	result, err := h.voterService.ImportCSV(c.Request.Context(), eventID, userID, file)
+	upload := service.RosterUpload{File: file}
+	if isXLSX(header.Filename, file) {
+		upload.XLSX = true
+		upload.Sheet = c.DefaultPostForm("sheet", c.Query("sheet"))
+	}
+
+	sync := c.Query("mode") == "sync"
+	disableMissing := sync && c.Query("disable_missing") == "true"
+
+	var result interface{}
+	if c.Query("dry_run") == "true" {
+		result, err = h.voterService.PreviewImport(c.Request.Context(), eventID, userID, upload, sync, disableMissing)
+	} else if sync {
+		result, err = h.voterService.SyncImport(c.Request.Context(), eventID, userID, upload, disableMissing)
+	} else {
+		result, err = h.voterService.Import(c.Request.Context(), eventID, userID, upload)
+	}
 	if err != nil {
 		_ = c.Error(err)
 		status := http.StatusInternalServerError
@@ -42,7 +70,7 @@ func (h *VoterHandler) Import(c *gin.Context) {
 			status = http.StatusForbidden
 		} else if err == service.ErrEventLocked {
 			status = http.StatusConflict
-		} else if err == service.ErrMaxVotersReached {
+		} else if err == service.ErrMaxVotersReached || err == service.ErrEmptyRoster {
 			status = http.StatusBadRequest
 		}
 		c.JSON(status, dto.ErrorResponse{OK: false, Error: err.Error()})
@@ -52,7 +80,24 @@ func (h *VoterHandler) Import(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: result})
 }
 
+// isXLSX reports whether an upload is a workbook, by extension or, for
+// misnamed files, by the ZIP signature every .xlsx starts with. The file is
+// rewound afterwards.
+func isXLSX(filename string, file io.ReadSeeker) bool {
+	if strings.EqualFold(filepath.Ext(filename), ".xlsx") {
+		return true
+	}
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(file, magic)
+	_, _ = file.Seek(0, io.SeekStart)
+	return n == 4 && bytes.Equal(magic, []byte("PK\x03\x04"))
+}
+
 // GET /api/events/:eventId/voters
+// Custom attributes filter as attr.<key>=<value>, e.g. ?attr.faculty=Teknik.
+// ?sort=<field>&order=asc|desc picks the ordering (default created_at desc);
+// pass next_cursor back as ?cursor= for the following page. The total is only
+// counted with ?include_total=true.
 func (h *VoterHandler) List(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	eventID := c.Param("eventId")
@@ -66,22 +111,53 @@ func (h *VoterHandler) List(c *gin.Context) {
 		hasVoted = &v
 	}
 
+	var attributes map[string]string
+	for name, values := range c.Request.URL.Query() {
+		if key := strings.TrimPrefix(name, "attr."); key != name && key != "" && len(values) > 0 {
+			if attributes == nil {
+				attributes = make(map[string]string)
+			}
+			attributes[key] = values[0]
+		}
+	}
+
+	sort := c.DefaultQuery("sort", "created_at")
+	desc := sort == "created_at"
+	switch c.Query("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "order must be asc or desc"})
+		return
+	}
+
 	params := dto.VoterListParams{
-		Query:    c.Query("q"),
-		Status:   c.Query("status"),
-		HasVoted: hasVoted,
-		Page:     page,
-		PerPage:  perPage,
+		Query:      c.Query("q"),
+		Status:     c.Query("status"),
+		HasVoted:   hasVoted,
+		Attributes: attributes,
+		Sort:       sort,
+		Desc:       desc,
+		Cursor:     c.Query("cursor"),
+		WithTotal:  c.Query("include_total") == "true",
+		Page:       page,
+		PerPage:    perPage,
 	}
 
 	result, err := h.voterService.List(c.Request.Context(), eventID, userID, params)
 	if err != nil {
 		_ = c.Error(err)
 		status := http.StatusInternalServerError
-		if err == service.ErrEventNotFound {
+		switch err {
+		case service.ErrEventNotFound:
 			status = http.StatusNotFound
-		} else if err == service.ErrEventForbidden {
+		case service.ErrEventForbidden:
 			status = http.StatusForbidden
+		case service.ErrInvalidSort, service.ErrInvalidCursor:
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, dto.ErrorResponse{OK: false, Error: err.Error()})
 		return
@@ -118,7 +194,9 @@ func (h *VoterHandler) ExportTokens(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	eventID := c.Param("eventId")
 
-	rows, err := h.voterService.ExportTokens(c.Request.Context(), eventID, userID)
+	includeLinks := c.Query("include_links") == "true"
+
+	rows, err := h.voterService.ExportTokens(c.Request.Context(), eventID, userID, includeLinks)
 	if err != nil {
 		_ = c.Error(err)
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{OK: false, Error: err.Error()})
@@ -129,17 +207,61 @@ func (h *VoterHandler) ExportTokens(c *gin.Context) {
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=tokens_%s.csv", eventID))
 
 	w := csv.NewWriter(c.Writer)
-	w.Write([]string{"full_name", "nim", "class_name", "token"})
+	header := []string{"full_name", "nim", "class_name", "token"}
+	if includeLinks {
+		header = append(header, "voting_link")
+	}
+	w.Write(header)
 	for _, row := range rows {
 		className := ""
 		if row.ClassName != nil {
 			className = *row.ClassName
 		}
-		w.Write([]string{row.FullName, row.NIMRaw, className, row.Token})
+		record := []string{row.FullName, row.NIMRaw, className, row.Token}
+		if includeLinks {
+			record = append(record, row.VotingLink)
+		}
+		w.Write(record)
 	}
 	w.Flush()
 }
 
+// GET /api/events/:eventId/voters/tokens/cards.pdf?per_page=8
+func (h *VoterHandler) TokenCards(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "8"))
+	layout, ok := util.CardLayouts[perPage]
+	if !ok {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "per_page must be one of 4, 8, 10, 12"})
+		return
+	}
+
+	event, cards, err := h.voterService.TokenCards(c.Request.Context(), eventID, userID)
+	if err != nil {
+		_ = c.Error(err)
+		status := http.StatusInternalServerError
+		if err == service.ErrEventNotFound {
+			status = http.StatusNotFound
+		} else if err == service.ErrEventForbidden {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := util.RenderTokenCardsPDF(&buf, event.Title, cards, layout); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{OK: false, Error: "failed to render token cards"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=token_cards_%s.pdf", eventID))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
 // GET /api/events/:eventId/voters/turnout/export
 func (h *VoterHandler) ExportTurnout(c *gin.Context) {
 	userID := middleware.GetUserID(c)
@@ -178,10 +300,170 @@ func (h *VoterHandler) DownloadTemplate(c *gin.Context) {
 
 	w := csv.NewWriter(c.Writer)
 	// Header row
-	w.Write([]string{"full_name", "nim", "class_name"})
+	w.Write([]string{"full_name", "nim", "class_name", "email", "phone", "dob"})
 	// Example rows so users understand the expected format
-	w.Write([]string{"Budi Santoso", "2023010001", "TI-A"})
-	w.Write([]string{"Siti Rahayu", "2023010002", "TI-B"})
-	w.Write([]string{"Andi Wijaya", "2023010003", ""})
+	w.Write([]string{"Budi Santoso", "2023010001", "TI-A", "budi@example.com", "081234567890", "2005-03-17"})
+	w.Write([]string{"Siti Rahayu", "2023010002", "TI-B", "siti@example.com", "", "21-11-2004"})
+	w.Write([]string{"Andi Wijaya", "2023010003", "", "", "", ""})
 	w.Flush()
 }
+
+// POST /api/events/:eventId/voters/:voterId/token/revoke
+func (h *VoterHandler) RevokeToken(c *gin.Context) {
+	var req dto.TokenActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "reason is required"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+	voterID := c.Param("voterId")
+
+	if err := h.voterService.RevokeToken(c.Request.Context(), eventID, voterID, userID, req.Reason); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "token revoked"})
+}
+
+// POST /api/events/:eventId/voters/:voterId/token/reissue
+func (h *VoterHandler) ReissueToken(c *gin.Context) {
+	var req dto.TokenActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "reason is required"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+	voterID := c.Param("voterId")
+
+	vt, err := h.voterService.ReissueToken(c.Request.Context(), eventID, voterID, userID, req.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: vt})
+}
+
+// GET /api/events/:eventId/voters/:voterId/tokens
+func (h *VoterHandler) TokenHistory(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+	voterID := c.Param("voterId")
+
+	tokens, err := h.voterService.TokenHistory(c.Request.Context(), eventID, voterID, userID)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: tokens})
+}
+
+// POST /api/events/:eventId/voters
+func (h *VoterHandler) Create(c *gin.Context) {
+	var req dto.CreateVoterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: "full_name and nim are required"})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+
+	voter, err := h.voterService.CreateVoter(c.Request.Context(), eventID, userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.SuccessResponse{OK: true, Data: voter})
+}
+
+// PATCH /api/events/:eventId/voters/:voterId
+func (h *VoterHandler) Update(c *gin.Context) {
+	var req dto.UpdateVoterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+	voterID := c.Param("voterId")
+
+	voter, err := h.voterService.UpdateVoter(c.Request.Context(), eventID, voterID, userID, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: voter})
+}
+
+// POST /api/events/:eventId/voters/:voterId/enable
+func (h *VoterHandler) Enable(c *gin.Context) {
+	h.setStatus(c, model.VoterStatusEligible)
+}
+
+// POST /api/events/:eventId/voters/:voterId/disable
+func (h *VoterHandler) Disable(c *gin.Context) {
+	h.setStatus(c, model.VoterStatusDisabled)
+}
+
+func (h *VoterHandler) setStatus(c *gin.Context, status model.VoterStatus) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+	voterID := c.Param("voterId")
+
+	voter, err := h.voterService.SetVoterStatus(c.Request.Context(), eventID, voterID, userID, status)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Data: voter})
+}
+
+// DELETE /api/events/:eventId/voters/:voterId
+func (h *VoterHandler) Delete(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	eventID := c.Param("eventId")
+	voterID := c.Param("voterId")
+
+	if err := h.voterService.DeleteVoter(c.Request.Context(), eventID, voterID, userID); err != nil {
+		_ = c.Error(err)
+		c.JSON(mapVoterError(err), dto.ErrorResponse{OK: false, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{OK: true, Message: "voter deleted"})
+}
+
+func mapVoterError(err error) int {
+	switch err {
+	case service.ErrEventNotFound, service.ErrVoterNotFound:
+		return http.StatusNotFound
+	case service.ErrEventForbidden:
+		return http.StatusForbidden
+	case service.ErrEventLocked, service.ErrTokenNotActive, service.ErrTokenNotReissuable,
+		service.ErrDuplicateNIM, service.ErrVoterHasVoted:
+		return http.StatusConflict
+	case service.ErrMaxVotersReached, service.ErrFullNameRequired, service.ErrInvalidNIM, service.ErrNIMFormat, service.ErrInvalidEmail,
+		service.ErrInvalidPhone, service.ErrInvalidDOB, service.ErrDOBRequired, service.ErrPhoneRequired,
+		service.ErrUnknownAttribute, service.ErrAttributeTooLong:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ── Enums ──
 
@@ -29,6 +32,70 @@ const (
 	TokenStatusRevoked TokenStatus = "REVOKED"
 )
 
+type DeliveryChannel string
+
+const (
+	DeliveryChannelEmail    DeliveryChannel = "EMAIL"
+	DeliveryChannelWhatsApp DeliveryChannel = "WHATSAPP"
+	DeliveryChannelSMS      DeliveryChannel = "SMS"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusQueued  DeliveryStatus = "QUEUED"
+	DeliveryStatusSent    DeliveryStatus = "SENT"
+	DeliveryStatusFailed  DeliveryStatus = "FAILED"
+	DeliveryStatusBounced DeliveryStatus = "BOUNCED"
+)
+
+type RegistrationStatus string
+
+const (
+	RegistrationStatusPending  RegistrationStatus = "PENDING"
+	RegistrationStatusApproved RegistrationStatus = "APPROVED"
+	RegistrationStatusRejected RegistrationStatus = "REJECTED"
+)
+
+// SecondFactor is the extra attribute a voter must supply alongside token and NIM.
+type SecondFactor string
+
+const (
+	SecondFactorDOB        SecondFactor = "DOB"
+	SecondFactorPhoneLast4 SecondFactor = "PHONE_LAST4"
+)
+
+type ProxyStatus string
+
+const (
+	ProxyStatusActive  ProxyStatus = "ACTIVE"
+	ProxyStatusUsed    ProxyStatus = "USED"
+	ProxyStatusRevoked ProxyStatus = "REVOKED"
+)
+
+type ImportJobStatus string
+
+const (
+	ImportJobStatusQueued    ImportJobStatus = "QUEUED"
+	ImportJobStatusRunning   ImportJobStatus = "RUNNING"
+	ImportJobStatusSucceeded ImportJobStatus = "SUCCEEDED"
+	ImportJobStatusFailed    ImportJobStatus = "FAILED"
+)
+
+type ImportMode string
+
+const (
+	ImportModeInsert ImportMode = "INSERT"
+	ImportModeSync   ImportMode = "SYNC"
+)
+
+type LockoutKind string
+
+const (
+	LockoutKindToken LockoutKind = "TOKEN"
+	LockoutKindNIM   LockoutKind = "NIM"
+)
+
 type OrderStatus string
 
 const (
@@ -68,8 +135,53 @@ type Event struct {
 	MaxSlates   int         `json:"max_slates" db:"max_slates"`
 	MaxVoters   int         `json:"max_voters" db:"max_voters"`
 	Package     Package     `json:"package" db:"package"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+	// Token policy — see util.TokenPolicy
+	TokenLength    int    `json:"token_length" db:"token_length"`
+	TokenAlphabet  string `json:"token_alphabet" db:"token_alphabet"`
+	TokenCheckChar bool   `json:"token_check_char" db:"token_check_char"`
+	// NIM policy — see util.NIMPolicy
+	NIMStripChars    string        `json:"nim_strip_chars" db:"nim_strip_chars"`
+	NIMCaseFold      bool          `json:"nim_case_fold" db:"nim_case_fold"`
+	NIMTrimZeros     bool          `json:"nim_trim_zeros" db:"nim_trim_zeros"`
+	NIMPattern       string        `json:"nim_pattern" db:"nim_pattern"`
+	RegistrationOpen bool          `json:"registration_open" db:"registration_open"`
+	SecondFactor     *SecondFactor `json:"second_factor" db:"second_factor"`
+	ProxyCap         int           `json:"proxy_cap" db:"proxy_cap"`
+	// VoterAttributes are the custom attribute keys defined for the roster.
+	VoterAttributes []string   `json:"voter_attributes" db:"voter_attributes"`
+	LockedAt        *time.Time `json:"locked_at" db:"locked_at"`
+	// ArchivedAt is set once the event's voter PII and tokens were purged.
+	ArchivedAt *time.Time `json:"archived_at" db:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// EventSettings are the event options carried over by clones and templates:
+// the token and NIM policies, second factor, proxy cap and voter attributes.
+type EventSettings struct {
+	TokenLength     int           `json:"token_length"`
+	TokenAlphabet   string        `json:"token_alphabet"`
+	TokenCheckChar  bool          `json:"token_check_char"`
+	NIMStripChars   string        `json:"nim_strip_chars"`
+	NIMCaseFold     bool          `json:"nim_case_fold"`
+	NIMTrimZeros    bool          `json:"nim_trim_zeros"`
+	NIMPattern      string        `json:"nim_pattern"`
+	SecondFactor    *SecondFactor `json:"second_factor"`
+	ProxyCap        int           `json:"proxy_cap"`
+	VoterAttributes []string      `json:"voter_attributes"`
+}
+
+type EventTemplate struct {
+	ID            string        `json:"id" db:"id"`
+	OwnerUserID   string        `json:"owner_user_id" db:"owner_user_id"`
+	Name          string        `json:"name" db:"name"`
+	SourceEventID *string       `json:"source_event_id" db:"source_event_id"`
+	Title         string        `json:"title" db:"title"`
+	Description   *string       `json:"description" db:"description"`
+	Settings      EventSettings `json:"settings" db:"settings"`
+	// Slates holds the slates and their members; ids are not meaningful.
+	Slates    []Slate   `json:"slates" db:"slates"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 type Slate struct {
@@ -95,26 +207,141 @@ type SlateMember struct {
 }
 
 type Voter struct {
-	ID            string      `json:"id" db:"id"`
-	EventID       string      `json:"event_id" db:"event_id"`
-	FullName      string      `json:"full_name" db:"full_name"`
-	NIMRaw        string      `json:"nim_raw" db:"nim_raw"`
-	NIMNormalized string      `json:"nim_normalized" db:"nim_normalized"`
-	ClassName     *string     `json:"class_name" db:"class_name"`
-	Status        VoterStatus `json:"status" db:"status"`
-	HasVoted      bool        `json:"has_voted" db:"has_voted"`
-	VotedAt       *time.Time  `json:"voted_at" db:"voted_at"`
-	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	ID            string  `json:"id" db:"id"`
+	EventID       string  `json:"event_id" db:"event_id"`
+	FullName      string  `json:"full_name" db:"full_name"`
+	NIMRaw        string  `json:"nim_raw" db:"nim_raw"`
+	NIMNormalized string  `json:"nim_normalized" db:"nim_normalized"`
+	ClassName     *string `json:"class_name" db:"class_name"`
+	Email         *string `json:"email" db:"email"`
+	Phone         *string `json:"phone" db:"phone"`
+	DOBHash       *string `json:"-" db:"dob_hash"`
+	// PhoneLast4Hash is the keyed hash checked by the PHONE_LAST4 second factor.
+	PhoneLast4Hash *string `json:"-" db:"phone_last4_hash"`
+	// Attributes holds values for the event's VoterAttributes.
+	Attributes map[string]string `json:"attributes" db:"attributes"`
+	Status     VoterStatus       `json:"status" db:"status"`
+	HasVoted   bool              `json:"has_voted" db:"has_voted"`
+	VotedAt    *time.Time        `json:"voted_at" db:"voted_at"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
 }
 
 type VoterToken struct {
-	ID       string      `json:"id" db:"id"`
-	EventID  string      `json:"event_id" db:"event_id"`
-	VoterID  string      `json:"voter_id" db:"voter_id"`
-	Token    string      `json:"token" db:"token"`
-	Status   TokenStatus `json:"status" db:"status"`
-	IssuedAt time.Time   `json:"issued_at" db:"issued_at"`
-	UsedAt   *time.Time  `json:"used_at" db:"used_at"`
+	ID           string      `json:"id" db:"id"`
+	EventID      string      `json:"event_id" db:"event_id"`
+	VoterID      string      `json:"voter_id" db:"voter_id"`
+	Token        string      `json:"token" db:"token"`
+	Status       TokenStatus `json:"status" db:"status"`
+	IssuedAt     time.Time   `json:"issued_at" db:"issued_at"`
+	UsedAt       *time.Time  `json:"used_at" db:"used_at"`
+	RevokedAt    *time.Time  `json:"revoked_at" db:"revoked_at"`
+	RevokeReason *string     `json:"revoke_reason" db:"revoke_reason"`
+}
+
+type TokenDelivery struct {
+	ID        string          `json:"id" db:"id"`
+	EventID   string          `json:"event_id" db:"event_id"`
+	VoterID   string          `json:"voter_id" db:"voter_id"`
+	Channel   DeliveryChannel `json:"channel" db:"channel"`
+	Recipient string          `json:"recipient" db:"recipient"`
+	Status    DeliveryStatus  `json:"status" db:"status"`
+	Attempts  int             `json:"attempts" db:"attempts"`
+	LastError *string         `json:"last_error" db:"last_error"`
+	QueuedAt  time.Time       `json:"queued_at" db:"queued_at"`
+	SentAt    *time.Time      `json:"sent_at" db:"sent_at"`
+	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type VoterRegistration struct {
+	ID            string             `json:"id" db:"id"`
+	EventID       string             `json:"event_id" db:"event_id"`
+	FullName      string             `json:"full_name" db:"full_name"`
+	NIMRaw        string             `json:"nim_raw" db:"nim_raw"`
+	NIMNormalized string             `json:"nim_normalized" db:"nim_normalized"`
+	ClassName     *string            `json:"class_name" db:"class_name"`
+	Email         *string            `json:"email" db:"email"`
+	Phone         *string            `json:"phone" db:"phone"`
+	DOBHash       *string            `json:"-" db:"dob_hash"`
+	Status        RegistrationStatus `json:"status" db:"status"`
+	RejectReason  *string            `json:"reject_reason" db:"reject_reason"`
+	VoterID       *string            `json:"voter_id" db:"voter_id"`
+	ReviewedBy    *string            `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt    *time.Time         `json:"reviewed_at" db:"reviewed_at"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+}
+
+// Proxy lets the holder cast the grantor's ballot on their behalf.
+type Proxy struct {
+	ID             string      `json:"id" db:"id"`
+	EventID        string      `json:"event_id" db:"event_id"`
+	GrantorVoterID string      `json:"grantor_voter_id" db:"grantor_voter_id"`
+	HolderVoterID  string      `json:"holder_voter_id" db:"holder_voter_id"`
+	Status         ProxyStatus `json:"status" db:"status"`
+	Note           *string     `json:"note" db:"note"`
+	CreatedBy      *string     `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UsedAt         *time.Time  `json:"used_at" db:"used_at"`
+	RevokedAt      *time.Time  `json:"revoked_at" db:"revoked_at"`
+}
+
+// ImportJob is a voter import run by the background worker. The uploaded file
+// is kept in the table but not loaded into this struct.
+type ImportJob struct {
+	ID             string          `json:"id" db:"id"`
+	EventID        string          `json:"event_id" db:"event_id"`
+	CreatedBy      *string         `json:"created_by" db:"created_by"`
+	Mode           ImportMode      `json:"mode" db:"mode"`
+	DisableMissing bool            `json:"disable_missing" db:"disable_missing"`
+	FileName       string          `json:"file_name" db:"file_name"`
+	Status         ImportJobStatus `json:"status" db:"status"`
+	TotalRows      int             `json:"total_rows" db:"total_rows"`
+	ProcessedRows  int             `json:"processed_rows" db:"processed_rows"`
+	Rejected       json.RawMessage `json:"rejected" db:"rejected"`
+	Result         json.RawMessage `json:"result" db:"result"`
+	Error          *string         `json:"error" db:"error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	StartedAt      *time.Time      `json:"started_at" db:"started_at"`
+	FinishedAt     *time.Time      `json:"finished_at" db:"finished_at"`
+}
+
+// MemberDirectory is a user's standing roster, e.g. a whole student body,
+// that events pull their voters from.
+type MemberDirectory struct {
+	ID          string    `json:"id" db:"id"`
+	OwnerUserID string    `json:"owner_user_id" db:"owner_user_id"`
+	Name        string    `json:"name" db:"name"`
+	MemberCount int       `json:"member_count" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type DirectoryMember struct {
+	ID            string  `json:"id" db:"id"`
+	DirectoryID   string  `json:"directory_id" db:"directory_id"`
+	FullName      string  `json:"full_name" db:"full_name"`
+	NIMRaw        string  `json:"nim_raw" db:"nim_raw"`
+	NIMNormalized string  `json:"nim_normalized" db:"nim_normalized"`
+	ClassName     *string `json:"class_name" db:"class_name"`
+	Email         *string `json:"email" db:"email"`
+	Phone         *string `json:"phone" db:"phone"`
+	// Dates of birth are not kept: their hashes are keyed per event.
+	Attributes map[string]string `json:"attributes" db:"attributes"`
+	// Active is false for members dropped by a sync; they are not pulled.
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type VoteLockout struct {
+	ID           string      `json:"id" db:"id"`
+	EventID      string      `json:"event_id" db:"event_id"`
+	Kind         LockoutKind `json:"kind" db:"kind"`
+	Subject      string      `json:"subject" db:"subject"`
+	Failures     int         `json:"failures" db:"failures"`
+	Lockouts     int         `json:"lockouts" db:"lockouts"`
+	LockedUntil  *time.Time  `json:"locked_until" db:"locked_until"`
+	LastFailedAt time.Time   `json:"last_failed_at" db:"last_failed_at"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
 }
 
 type Ballot struct {
@@ -133,6 +360,18 @@ type AuditLog struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
+// AccountAuditLog is an entry in a user's account-level audit trail. EventID
+// may refer to an event that no longer exists.
+type AccountAuditLog struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	ActorUserID *string   `json:"actor_user_id" db:"actor_user_id"`
+	EventID     *string   `json:"event_id" db:"event_id"`
+	Action      string    `json:"action" db:"action"`
+	Meta        string    `json:"meta" db:"meta"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
 type Order struct {
 	ID              string      `json:"id" db:"id"`
 	EventID         string      `json:"event_id" db:"event_id"`
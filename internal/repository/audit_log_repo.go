@@ -25,6 +25,16 @@ func (r *AuditLogRepo) Create(ctx context.Context, eventID string, actorUserID *
 	return err
 }
 
+// CreateInTx records an entry that must commit or roll back with the change
+// it describes.
+func (r *AuditLogRepo) CreateInTx(ctx context.Context, tx *sql.Tx, eventID string, actorUserID *string, action string, meta string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_logs (event_id, actor_user_id, action, meta) VALUES ($1, $2, $3, $4::jsonb)`,
+		eventID, actorUserID, action, meta,
+	)
+	return err
+}
+
 func (r *AuditLogRepo) List(ctx context.Context, eventID string, page, perPage int) ([]model.AuditLog, int, error) {
 	var total int
 	err := r.db.QueryRowContext(ctx,
@@ -63,6 +73,73 @@ func (r *AuditLogRepo) List(ctx context.Context, eventID string, page, perPage i
 	return logs, total, rows.Err()
 }
 
+// ScrubVoterPII removes voter details from the event's entries while keeping
+// the entries themselves: the before/after snapshots of voter changes and the
+// subjects of lockouts.
+func (r *AuditLogRepo) ScrubVoterPII(ctx context.Context, tx *sql.Tx, eventID string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE audit_logs SET meta = meta - 'before' - 'after'
+		 WHERE event_id = $1 AND action IN ('voter.created', 'voter.updated', 'voter.deleted')`,
+		eventID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE audit_logs SET meta = meta - 'subject'
+		 WHERE event_id = $1 AND action IN ('vote.lockout', 'vote.lockout_cleared')`,
+		eventID,
+	)
+	return err
+}
+
+// CreateAccountInTx records an entry in userID's account-level trail.
+func (r *AuditLogRepo) CreateAccountInTx(ctx context.Context, tx *sql.Tx, userID string, actorUserID, eventID *string, action string, meta string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO account_audit_logs (user_id, actor_user_id, event_id, action, meta) VALUES ($1, $2, $3, $4, $5::jsonb)`,
+		userID, actorUserID, eventID, action, meta,
+	)
+	return err
+}
+
+func (r *AuditLogRepo) ListAccount(ctx context.Context, userID string, page, perPage int) ([]model.AccountAuditLog, int, error) {
+	var total int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM account_audit_logs WHERE user_id = $1`, userID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if perPage <= 0 {
+		perPage = 20
+	}
+	offset := (page - 1) * perPage
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf(`SELECT id, user_id, actor_user_id, event_id, action, meta, created_at
+		 FROM account_audit_logs WHERE user_id = $1 ORDER BY created_at DESC LIMIT %d OFFSET %d`, perPage, offset),
+		userID,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var logs []model.AccountAuditLog
+	for rows.Next() {
+		var l model.AccountAuditLog
+		if err := rows.Scan(&l.ID, &l.UserID, &l.ActorUserID, &l.EventID, &l.Action, &l.Meta, &l.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, rows.Err()
+}
+
 // ── Convenience method to convert to DTO ──
 
 func AuditLogsToDTO(logs []model.AuditLog) []dto.AuditLogDTO {
@@ -78,3 +155,18 @@ func AuditLogsToDTO(logs []model.AuditLog) []dto.AuditLogDTO {
 	}
 	return result
 }
+
+func AccountAuditLogsToDTO(logs []model.AccountAuditLog) []dto.AccountAuditLogDTO {
+	result := make([]dto.AccountAuditLogDTO, len(logs))
+	for i, l := range logs {
+		result[i] = dto.AccountAuditLogDTO{
+			ID:          l.ID,
+			Action:      l.Action,
+			ActorUserID: l.ActorUserID,
+			EventID:     l.EventID,
+			Meta:        l.Meta,
+			CreatedAt:   l.CreatedAt,
+		}
+	}
+	return result
+}
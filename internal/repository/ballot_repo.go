@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/amard/pemilo-golang/internal/dto"
@@ -61,6 +62,101 @@ func (r *BallotRepo) GetTurnoutCounts(ctx context.Context, eventID string) (tota
 	return
 }
 
+// GetTurnoutByClass returns total voters and voted count per class_name,
+// ordered by class. Voters without a class are grouped under "".
+func (r *BallotRepo) GetTurnoutByClass(ctx context.Context, eventID string) ([]dto.ClassTurnout, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT COALESCE(class_name, '') AS class, COUNT(*), COUNT(*) FILTER (WHERE has_voted = true)
+		 FROM voters
+		 WHERE event_id = $1
+		 GROUP BY class
+		 ORDER BY class`,
+		eventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []dto.ClassTurnout
+	for rows.Next() {
+		var ct dto.ClassTurnout
+		if err := rows.Scan(&ct.ClassName, &ct.TotalVoters, &ct.VotedCount); err != nil {
+			return nil, err
+		}
+		result = append(result, ct)
+	}
+	return result, rows.Err()
+}
+
+// GetNotVoted returns a page of voters who have not voted, by class then
+// name, together with the total. A nil className lists every class; "" means
+// voters without a class. The has_voted filter is served by
+// idx_voters_event_voted.
+func (r *BallotRepo) GetNotVoted(ctx context.Context, eventID string, className *string, limit, offset int) ([]dto.NotVotedVoter, int, error) {
+	where := `event_id = $1 AND has_voted = false`
+	args := []interface{}{eventID}
+	if className != nil {
+		where += ` AND COALESCE(class_name, '') = $2`
+		args = append(args, *className)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM voters WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf(`SELECT id, full_name, nim_raw, class_name, status
+		 FROM voters WHERE %s
+		 ORDER BY class_name NULLS FIRST, full_name, id
+		 LIMIT %d OFFSET %d`, where, limit, offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var result []dto.NotVotedVoter
+	for rows.Next() {
+		var v dto.NotVotedVoter
+		if err := rows.Scan(&v.ID, &v.FullName, &v.NIMRaw, &v.ClassName, &v.Status); err != nil {
+			return nil, 0, err
+		}
+		result = append(result, v)
+	}
+	return result, total, rows.Err()
+}
+
+// GetTurnoutByAttribute returns total voters and voted count per value of a
+// custom voter attribute, largest group first. Voters without a value are
+// grouped under "". Only the voters table is read, never ballots.
+func (r *BallotRepo) GetTurnoutByAttribute(ctx context.Context, eventID, key string) ([]dto.TurnoutSegment, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT COALESCE(attributes->>$2, '') AS value, COUNT(*), COUNT(*) FILTER (WHERE has_voted = true)
+		 FROM voters
+		 WHERE event_id = $1
+		 GROUP BY value
+		 ORDER BY COUNT(*) DESC, value`,
+		eventID, key,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []dto.TurnoutSegment
+	for rows.Next() {
+		var seg dto.TurnoutSegment
+		if err := rows.Scan(&seg.Value, &seg.TotalVoters, &seg.VotedCount); err != nil {
+			return nil, err
+		}
+		result = append(result, seg)
+	}
+	return result, rows.Err()
+}
+
 // GetLatestVoters returns the most recent voters who have voted.
 func (r *BallotRepo) GetLatestVoters(ctx context.Context, eventID string, limit int) ([]dto.LatestVoter, error) {
 	rows, err := r.db.QueryContext(ctx,
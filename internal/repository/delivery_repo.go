@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+type DeliveryRepo struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepo(db *sql.DB) *DeliveryRepo {
+	return &DeliveryRepo{db: db}
+}
+
+// PendingDelivery is a queued delivery joined with what is needed to render it.
+type PendingDelivery struct {
+	ID        string
+	EventID   string
+	Recipient string
+	FullName  string
+	NIMRaw    string
+	Token     string
+}
+
+// EnqueueForEvent queues a delivery for every eligible voter that has an ACTIVE
+// token and a contact for the channel. Unless resend is set, deliveries that
+// were already SENT or BOUNCED are left untouched; QUEUED and FAILED ones are
+// always (re)queued.
+func (r *DeliveryRepo) EnqueueForEvent(ctx context.Context, eventID string, channel model.DeliveryChannel, resend bool) (int64, error) {
+	contactCol, err := contactColumn(channel)
+	if err != nil {
+		return 0, err
+	}
+
+	requeueStatuses := `'QUEUED','FAILED'`
+	if resend {
+		requeueStatuses = `'QUEUED','FAILED','SENT','BOUNCED'`
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO token_deliveries (event_id, voter_id, channel, recipient)
+		 SELECT v.event_id, v.id, $2, v.%[1]s
+		 FROM voters v
+		 JOIN voter_tokens vt ON vt.voter_id = v.id AND vt.status = 'ACTIVE'
+		 WHERE v.event_id = $1 AND v.status = 'ELIGIBLE' AND v.%[1]s IS NOT NULL
+		 ON CONFLICT (voter_id, channel) DO UPDATE SET
+			recipient = EXCLUDED.recipient,
+			status = 'QUEUED',
+			last_error = NULL,
+			queued_at = now(),
+			updated_at = now()
+		 WHERE token_deliveries.status IN (%[2]s)`, contactCol, requeueStatuses),
+		eventID, string(channel),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RequeueFailed moves FAILED deliveries of an event back to QUEUED.
+func (r *DeliveryRepo) RequeueFailed(ctx context.Context, eventID string, channel model.DeliveryChannel) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE token_deliveries SET status = 'QUEUED', queued_at = now(), updated_at = now()
+		 WHERE event_id = $1 AND channel = $2 AND status = 'FAILED'`,
+		eventID, string(channel),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListQueued returns up to limit QUEUED deliveries, oldest first.
+func (r *DeliveryRepo) ListQueued(ctx context.Context, eventID string, channel model.DeliveryChannel, limit int) ([]PendingDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT d.id, d.event_id, d.recipient, v.full_name, v.nim_raw, vt.token
+		 FROM token_deliveries d
+		 JOIN voters v ON v.id = d.voter_id
+		 JOIN voter_tokens vt ON vt.voter_id = d.voter_id AND vt.status = 'ACTIVE'
+		 WHERE d.event_id = $1 AND d.channel = $2 AND d.status = 'QUEUED'
+		 ORDER BY d.queued_at
+		 LIMIT $3`,
+		eventID, string(channel), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []PendingDelivery
+	for rows.Next() {
+		var p PendingDelivery
+		if err := rows.Scan(&p.ID, &p.EventID, &p.Recipient, &p.FullName, &p.NIMRaw, &p.Token); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// ListQueuedEvents returns the event/channel pairs that still have QUEUED deliveries.
+func (r *DeliveryRepo) ListQueuedEvents(ctx context.Context) (map[string][]model.DeliveryChannel, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT event_id, channel FROM token_deliveries WHERE status = 'QUEUED'`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	m := make(map[string][]model.DeliveryChannel)
+	for rows.Next() {
+		var eventID string
+		var channel model.DeliveryChannel
+		if err := rows.Scan(&eventID, &channel); err != nil {
+			return nil, err
+		}
+		m[eventID] = append(m[eventID], channel)
+	}
+	return m, rows.Err()
+}
+
+// LockDispatch takes the session advisory lock that makes one process the
+// dispatcher of an event's deliveries on a channel. ok is false when another
+// process holds it. The lock lives on a dedicated connection, so it is freed
+// if the holder dies; otherwise release must be called when done.
+func (r *DeliveryRepo) LockDispatch(ctx context.Context, eventID string, channel model.DeliveryChannel) (release func(), ok bool, err error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	key := "token_deliveries:" + eventID + ":" + string(channel)
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtextextended($1, 0))`, key).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+	release = func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, key)
+		conn.Close()
+	}
+	return release, true, nil
+}
+
+// DropOrphaned marks QUEUED deliveries whose voter no longer has an ACTIVE
+// token as FAILED so the dispatcher does not keep picking them up.
+func (r *DeliveryRepo) DropOrphaned(ctx context.Context, eventID string, channel model.DeliveryChannel) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE token_deliveries d SET status = 'FAILED', last_error = 'no active token', updated_at = now()
+		 WHERE d.event_id = $1 AND d.channel = $2 AND d.status = 'QUEUED'
+		   AND NOT EXISTS (SELECT 1 FROM voter_tokens vt WHERE vt.voter_id = d.voter_id AND vt.status = 'ACTIVE')`,
+		eventID, string(channel),
+	)
+	return err
+}
+
+func (r *DeliveryRepo) MarkSent(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE token_deliveries SET status = 'SENT', attempts = attempts + 1, last_error = NULL, sent_at = now(), updated_at = now()
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+// MarkFailed records a failed attempt with either FAILED or BOUNCED status.
+func (r *DeliveryRepo) MarkFailed(ctx context.Context, id string, status model.DeliveryStatus, reason string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE token_deliveries SET status = $2, attempts = attempts + 1, last_error = $3, updated_at = now()
+		 WHERE id = $1`,
+		id, string(status), reason,
+	)
+	return err
+}
+
+func (r *DeliveryRepo) ListByEvent(ctx context.Context, eventID string, status string) ([]model.TokenDelivery, error) {
+	query := `SELECT id, event_id, voter_id, channel, recipient, status, attempts, last_error, queued_at, sent_at, updated_at
+		 FROM token_deliveries WHERE event_id = $1`
+	args := []interface{}{eventID}
+	if status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY updated_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.TokenDelivery
+	for rows.Next() {
+		var d model.TokenDelivery
+		if err := rows.Scan(&d.ID, &d.EventID, &d.VoterID, &d.Channel, &d.Recipient, &d.Status, &d.Attempts, &d.LastError, &d.QueuedAt, &d.SentAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// contactColumn maps a delivery channel to the voters column holding its address.
+func contactColumn(channel model.DeliveryChannel) (string, error) {
+	switch channel {
+	case model.DeliveryChannelEmail:
+		return "email", nil
+	case model.DeliveryChannelWhatsApp, model.DeliveryChannelSMS:
+		return "phone", nil
+	default:
+		return "", fmt.Errorf("unsupported delivery channel %q", channel)
+	}
+}
+
+// PurgeEvent deletes the event's token deliveries and their recipients, for archival.
+func (r *DeliveryRepo) PurgeEvent(ctx context.Context, tx *sql.Tx, eventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM token_deliveries WHERE event_id = $1`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/lib/pq"
+)
+
+type DirectoryRepo struct {
+	db *sql.DB
+}
+
+func NewDirectoryRepo(db *sql.DB) *DirectoryRepo {
+	return &DirectoryRepo{db: db}
+}
+
+const directoryColumns = `id, owner_user_id, name, created_at, updated_at`
+
+func scanDirectory(s rowScanner, d *model.MemberDirectory) error {
+	return s.Scan(&d.ID, &d.OwnerUserID, &d.Name, &d.CreatedAt, &d.UpdatedAt)
+}
+
+const directoryMemberColumns = `id, directory_id, full_name, nim_raw, nim_normalized, class_name, email, phone, attributes, active, created_at, updated_at`
+
+func scanDirectoryMember(s rowScanner, m *model.DirectoryMember) error {
+	var attrs []byte
+	if err := s.Scan(&m.ID, &m.DirectoryID, &m.FullName, &m.NIMRaw, &m.NIMNormalized, &m.ClassName, &m.Email, &m.Phone, &attrs, &m.Active, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(attrs, &m.Attributes)
+}
+
+func (r *DirectoryRepo) Create(ctx context.Context, ownerUserID, name string) (*model.MemberDirectory, error) {
+	var d model.MemberDirectory
+	err := scanDirectory(r.db.QueryRowContext(ctx,
+		`INSERT INTO member_directories (owner_user_id, name) VALUES ($1, $2) RETURNING `+directoryColumns,
+		ownerUserID, name,
+	), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *DirectoryRepo) GetByID(ctx context.Context, id string) (*model.MemberDirectory, error) {
+	var d model.MemberDirectory
+	err := scanDirectory(r.db.QueryRowContext(ctx,
+		`SELECT `+directoryColumns+` FROM member_directories WHERE id = $1`, id,
+	), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListByOwner returns the user's directories with their active member counts.
+func (r *DirectoryRepo) ListByOwner(ctx context.Context, ownerUserID string) ([]model.MemberDirectory, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT d.id, d.owner_user_id, d.name, d.created_at, d.updated_at,
+		        (SELECT COUNT(*) FROM directory_members m WHERE m.directory_id = d.id AND m.active)
+		 FROM member_directories d
+		 WHERE d.owner_user_id = $1
+		 ORDER BY d.created_at DESC`,
+		ownerUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var directories []model.MemberDirectory
+	for rows.Next() {
+		var d model.MemberDirectory
+		if err := rows.Scan(&d.ID, &d.OwnerUserID, &d.Name, &d.CreatedAt, &d.UpdatedAt, &d.MemberCount); err != nil {
+			return nil, err
+		}
+		directories = append(directories, d)
+	}
+	return directories, rows.Err()
+}
+
+func (r *DirectoryRepo) Rename(ctx context.Context, id, name string) (*model.MemberDirectory, error) {
+	var d model.MemberDirectory
+	err := scanDirectory(r.db.QueryRowContext(ctx,
+		`UPDATE member_directories SET name = $2, updated_at = now() WHERE id = $1 RETURNING `+directoryColumns,
+		id, name,
+	), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Delete removes the directory and its members. Voters already pulled into
+// events are copies and stay.
+func (r *DirectoryRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM member_directories WHERE id = $1`, id)
+	return err
+}
+
+// DirectoryMemberFilter selects directory members. Empty fields match all.
+type DirectoryMemberFilter struct {
+	Query      string
+	ClassNames []string
+	Attributes map[string]string
+	// Active restricts to active (true) or deactivated (false) members.
+	Active *bool
+}
+
+func (f DirectoryMemberFilter) where(directoryID string) (string, []interface{}) {
+	where := []string{"directory_id = $1"}
+	args := []interface{}{directoryID}
+	argIdx := 2
+
+	if f.Query != "" {
+		where = append(where, fmt.Sprintf("(full_name ILIKE $%d OR nim_raw ILIKE $%d)", argIdx, argIdx))
+		args = append(args, "%"+f.Query+"%")
+		argIdx++
+	}
+	if len(f.ClassNames) > 0 {
+		where = append(where, fmt.Sprintf("class_name = ANY($%d)", argIdx))
+		args = append(args, pq.Array(f.ClassNames))
+		argIdx++
+	}
+	if len(f.Attributes) > 0 {
+		where = append(where, fmt.Sprintf("attributes @> $%d::jsonb", argIdx))
+		args = append(args, attributesJSON(f.Attributes))
+		argIdx++
+	}
+	if f.Active != nil {
+		where = append(where, fmt.Sprintf("active = $%d", argIdx))
+		args = append(args, *f.Active)
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// ListMembers returns one page of matching members ordered by name, and the
+// number of matches.
+func (r *DirectoryRepo) ListMembers(ctx context.Context, directoryID string, filter DirectoryMemberFilter, limit, offset int) ([]model.DirectoryMember, int, error) {
+	where, args := filter.where(directoryID)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM directory_members WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	members, err := r.queryMembers(ctx,
+		fmt.Sprintf(`SELECT %s FROM directory_members WHERE %s ORDER BY full_name, id LIMIT %d OFFSET %d`, directoryMemberColumns, where, limit, offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// ListMatching returns every member matching the filter, for pulling into an
+// event.
+func (r *DirectoryRepo) ListMatching(ctx context.Context, directoryID string, filter DirectoryMemberFilter) ([]model.DirectoryMember, error) {
+	where, args := filter.where(directoryID)
+	return r.queryMembers(ctx, `SELECT `+directoryMemberColumns+` FROM directory_members WHERE `+where+` ORDER BY full_name, id`, args...)
+}
+
+func (r *DirectoryRepo) queryMembers(ctx context.Context, query string, args ...interface{}) ([]model.DirectoryMember, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []model.DirectoryMember
+	for rows.Next() {
+		var m model.DirectoryMember
+		if err := scanDirectoryMember(rows, &m); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// DirectoryMemberRow is a parsed directory roster row.
+type DirectoryMemberRow struct {
+	FullName      string
+	NIMRaw        string
+	NIMNormalized string
+	ClassName     string
+	Email         string
+	Phone         string
+	Attributes    map[string]string
+}
+
+// UpsertMembers writes rows to the directory with a single statement inside
+// the caller's transaction. New NIMs are inserted. With update, existing
+// members whose fields differ are overwritten and reactivated; otherwise they
+// are left alone. It returns the NIMs inserted and updated; NIMs in neither
+// were unchanged or, without update, already present.
+func (r *DirectoryRepo) UpsertMembers(ctx context.Context, tx *sql.Tx, directoryID string, rows []DirectoryMemberRow, update bool) (inserted, updated map[string]bool, err error) {
+	inserted, updated = make(map[string]bool), make(map[string]bool)
+	if len(rows) == 0 {
+		return inserted, updated, nil
+	}
+
+	n := len(rows)
+	fullNames, nimRaws, nimNorms := make([]string, n), make([]string, n), make([]string, n)
+	classNames, emails, phones, attributes := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	for i, row := range rows {
+		fullNames[i], nimRaws[i], nimNorms[i] = row.FullName, row.NIMRaw, row.NIMNormalized
+		classNames[i], emails[i], phones[i], attributes[i] = row.ClassName, row.Email, row.Phone, attributesJSON(row.Attributes)
+	}
+
+	conflict := `DO NOTHING`
+	if update {
+		conflict = `DO UPDATE SET
+		   full_name = EXCLUDED.full_name, nim_raw = EXCLUDED.nim_raw, class_name = EXCLUDED.class_name,
+		   email = EXCLUDED.email, phone = EXCLUDED.phone, attributes = EXCLUDED.attributes,
+		   active = true, updated_at = now()
+		 WHERE (m.full_name, m.nim_raw, m.class_name, m.email, m.phone, m.attributes, m.active)
+		   IS DISTINCT FROM (EXCLUDED.full_name, EXCLUDED.nim_raw, EXCLUDED.class_name, EXCLUDED.email, EXCLUDED.phone, EXCLUDED.attributes, true)`
+	}
+
+	returned, err := tx.QueryContext(ctx,
+		`INSERT INTO directory_members AS m (directory_id, full_name, nim_raw, nim_normalized, class_name, email, phone, attributes)
+		 SELECT $1, r.full_name, r.nim_raw, r.nim_normalized, NULLIF(r.class_name, ''), NULLIF(r.email, ''), NULLIF(r.phone, ''), r.attributes::jsonb
+		 FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[])
+		   AS r(full_name, nim_raw, nim_normalized, class_name, email, phone, attributes)
+		 ON CONFLICT ON CONSTRAINT uq_directory_members_nim `+conflict+`
+		 RETURNING m.nim_normalized, (xmax = 0)`,
+		directoryID, pq.Array(fullNames), pq.Array(nimRaws), pq.Array(nimNorms),
+		pq.Array(classNames), pq.Array(emails), pq.Array(phones), pq.Array(attributes),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer returned.Close()
+
+	for returned.Next() {
+		var nim string
+		var isInsert bool
+		if err := returned.Scan(&nim, &isInsert); err != nil {
+			return nil, nil, err
+		}
+		if isInsert {
+			inserted[nim] = true
+		} else {
+			updated[nim] = true
+		}
+	}
+	if err := returned.Err(); err != nil {
+		return nil, nil, err
+	}
+	return inserted, updated, nil
+}
+
+// DeactivateMissing deactivates the directory's active members whose NIM is
+// not in keep.
+func (r *DirectoryRepo) DeactivateMissing(ctx context.Context, tx *sql.Tx, directoryID string, keep []string) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE directory_members SET active = false, updated_at = now()
+		 WHERE directory_id = $1 AND active AND NOT (nim_normalized = ANY($2))`,
+		directoryID, pq.Array(keep),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Touch bumps the directory's updated_at after its members changed.
+func (r *DirectoryRepo) Touch(ctx context.Context, tx *sql.Tx, id string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE member_directories SET updated_at = now() WHERE id = $1`, id)
+	return err
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/lib/pq"
 )
 
 type EventRepo struct {
@@ -15,26 +17,70 @@ func NewEventRepo(db *sql.DB) *EventRepo {
 	return &EventRepo{db: db}
 }
 
-func (r *EventRepo) Create(ctx context.Context, ownerID, title string, description *string, opensAt, closesAt *string, maxSlates, maxVoters int, pkg string) (*model.Event, error) {
+const eventColumns = `id, owner_user_id, title, description, status, opens_at, closes_at, max_slates, max_voters, package,
+		 token_length, token_alphabet, token_check_char, nim_strip_chars, nim_case_fold, nim_trim_zeros, nim_pattern, registration_open, second_factor, proxy_cap, voter_attributes, locked_at, archived_at, created_at, updated_at`
+
+func scanEvent(s rowScanner, e *model.Event) error {
+	return s.Scan(&e.ID, &e.OwnerUserID, &e.Title, &e.Description, &e.Status, &e.OpensAt, &e.ClosesAt, &e.MaxSlates, &e.MaxVoters, &e.Package,
+		&e.TokenLength, &e.TokenAlphabet, &e.TokenCheckChar, &e.NIMStripChars, &e.NIMCaseFold, &e.NIMTrimZeros, &e.NIMPattern, &e.RegistrationOpen, &e.SecondFactor, &e.ProxyCap, pq.Array(&e.VoterAttributes), &e.LockedAt, &e.ArchivedAt, &e.CreatedAt, &e.UpdatedAt)
+}
+
+func (r *EventRepo) Create(ctx context.Context, ownerID, title string, description *string, opensAt, closesAt *string, maxSlates, maxVoters int, pkg string, tokenLength int, tokenAlphabet string, tokenCheckChar bool) (*model.Event, error) {
 	var e model.Event
-	err := r.db.QueryRowContext(ctx,
-		`INSERT INTO events (owner_user_id, title, description, opens_at, closes_at, max_slates, max_voters, package)
-		 VALUES ($1, $2, $3, $4::timestamptz, $5::timestamptz, $6, $7, $8)
-		 RETURNING id, owner_user_id, title, description, status, opens_at, closes_at, max_slates, max_voters, package, created_at, updated_at`,
-		ownerID, title, description, opensAt, closesAt, maxSlates, maxVoters, pkg,
-	).Scan(&e.ID, &e.OwnerUserID, &e.Title, &e.Description, &e.Status, &e.OpensAt, &e.ClosesAt, &e.MaxSlates, &e.MaxVoters, &e.Package, &e.CreatedAt, &e.UpdatedAt)
+	err := scanEvent(r.db.QueryRowContext(ctx,
+		`INSERT INTO events (owner_user_id, title, description, opens_at, closes_at, max_slates, max_voters, package, token_length, token_alphabet, token_check_char)
+		 VALUES ($1, $2, $3, $4::timestamptz, $5::timestamptz, $6, $7, $8, $9, $10, $11)
+		 RETURNING `+eventColumns,
+		ownerID, title, description, opensAt, closesAt, maxSlates, maxVoters, pkg, tokenLength, tokenAlphabet, tokenCheckChar,
+	), &e)
 	if err != nil {
 		return nil, err
 	}
 	return &e, nil
 }
 
+// CreateInTx inserts a DRAFT event with e's title, description, limits,
+// package and settings; its schedule and registration are left unset.
+func (r *EventRepo) CreateInTx(ctx context.Context, tx *sql.Tx, ownerID string, e *model.Event) (*model.Event, error) {
+	attributes := e.VoterAttributes
+	if attributes == nil {
+		attributes = []string{}
+	}
+	var created model.Event
+	err := scanEvent(tx.QueryRowContext(ctx,
+		`INSERT INTO events (owner_user_id, title, description, max_slates, max_voters, package,
+		                     token_length, token_alphabet, token_check_char, nim_strip_chars, nim_case_fold, nim_trim_zeros, nim_pattern,
+		                     second_factor, proxy_cap, voter_attributes)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		 RETURNING `+eventColumns,
+		ownerID, e.Title, e.Description, e.MaxSlates, e.MaxVoters, string(e.Package),
+		e.TokenLength, e.TokenAlphabet, e.TokenCheckChar, e.NIMStripChars, e.NIMCaseFold, e.NIMTrimZeros, e.NIMPattern,
+		e.SecondFactor, e.ProxyCap, pq.Array(attributes),
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
 func (r *EventRepo) GetByID(ctx context.Context, id string) (*model.Event, error) {
 	var e model.Event
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, owner_user_id, title, description, status, opens_at, closes_at, max_slates, max_voters, package, created_at, updated_at
-		 FROM events WHERE id = $1`, id,
-	).Scan(&e.ID, &e.OwnerUserID, &e.Title, &e.Description, &e.Status, &e.OpensAt, &e.ClosesAt, &e.MaxSlates, &e.MaxVoters, &e.Package, &e.CreatedAt, &e.UpdatedAt)
+	err := scanEvent(r.db.QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE id = $1`, id,
+	), &e)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// LockForUpdate loads an event and locks its row for the rest of the
+// transaction, serializing changes that depend on its limits.
+func (r *EventRepo) LockForUpdate(ctx context.Context, tx *sql.Tx, id string) (*model.Event, error) {
+	var e model.Event
+	err := scanEvent(tx.QueryRowContext(ctx,
+		`SELECT `+eventColumns+` FROM events WHERE id = $1 FOR UPDATE`, id,
+	), &e)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +89,7 @@ func (r *EventRepo) GetByID(ctx context.Context, id string) (*model.Event, error
 
 func (r *EventRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.Event, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, owner_user_id, title, description, status, opens_at, closes_at, max_slates, max_voters, package, created_at, updated_at
-		 FROM events WHERE owner_user_id = $1 ORDER BY created_at DESC`, ownerID,
+		`SELECT `+eventColumns+` FROM events WHERE owner_user_id = $1 ORDER BY created_at DESC`, ownerID,
 	)
 	if err != nil {
 		return nil, err
@@ -54,7 +99,7 @@ func (r *EventRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.Ev
 	var events []model.Event
 	for rows.Next() {
 		var e model.Event
-		if err := rows.Scan(&e.ID, &e.OwnerUserID, &e.Title, &e.Description, &e.Status, &e.OpensAt, &e.ClosesAt, &e.MaxSlates, &e.MaxVoters, &e.Package, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if err := scanEvent(rows, &e); err != nil {
 			return nil, err
 		}
 		events = append(events, e)
@@ -62,19 +107,31 @@ func (r *EventRepo) ListByOwner(ctx context.Context, ownerID string) ([]model.Ev
 	return events, rows.Err()
 }
 
-func (r *EventRepo) Update(ctx context.Context, id string, title *string, description *string, opensAt, closesAt *string) (*model.Event, error) {
+// UpdateInTx saves an event's details and settings in one statement. Nil
+// details are left as they are; settings are written as given.
+func (r *EventRepo) UpdateInTx(ctx context.Context, tx *sql.Tx, id string, title, description, opensAt, closesAt *string, settings model.EventSettings, registrationOpen bool) (*model.Event, error) {
+	attributes := settings.VoterAttributes
+	if attributes == nil {
+		attributes = []string{}
+	}
 	var e model.Event
-	err := r.db.QueryRowContext(ctx,
+	err := scanEvent(tx.QueryRowContext(ctx,
 		`UPDATE events SET
 			title = COALESCE($2, title),
 			description = COALESCE($3, description),
 			opens_at = COALESCE($4::timestamptz, opens_at),
 			closes_at = COALESCE($5::timestamptz, closes_at),
+			token_length = $6, token_alphabet = $7, token_check_char = $8,
+			nim_strip_chars = $9, nim_case_fold = $10, nim_trim_zeros = $11, nim_pattern = $12,
+			second_factor = $13, proxy_cap = $14, voter_attributes = $15, registration_open = $16,
 			updated_at = now()
 		 WHERE id = $1
-		 RETURNING id, owner_user_id, title, description, status, opens_at, closes_at, max_slates, max_voters, package, created_at, updated_at`,
+		 RETURNING `+eventColumns,
 		id, title, description, opensAt, closesAt,
-	).Scan(&e.ID, &e.OwnerUserID, &e.Title, &e.Description, &e.Status, &e.OpensAt, &e.ClosesAt, &e.MaxSlates, &e.MaxVoters, &e.Package, &e.CreatedAt, &e.UpdatedAt)
+		settings.TokenLength, settings.TokenAlphabet, settings.TokenCheckChar,
+		settings.NIMStripChars, settings.NIMCaseFold, settings.NIMTrimZeros, settings.NIMPattern,
+		settings.SecondFactor, settings.ProxyCap, pq.Array(attributes), registrationOpen,
+	), &e)
 	if err != nil {
 		return nil, err
 	}
@@ -83,12 +140,102 @@ func (r *EventRepo) Update(ctx context.Context, id string, title *string, descri
 
 func (r *EventRepo) UpdateStatus(ctx context.Context, id string, status model.EventStatus) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE events SET status = $2, updated_at = now() WHERE id = $1`,
+		`UPDATE events SET status = $2, updated_at = now(),
+		        locked_at = CASE WHEN $2 = 'LOCKED' THEN now() ELSE locked_at END
+		 WHERE id = $1`,
 		id, string(status),
 	)
 	return err
 }
 
+// MarkScheduled moves drafts whose opens_at is still ahead to SCHEDULED and
+// returns their ids.
+func (r *EventRepo) MarkScheduled(ctx context.Context, tx *sql.Tx, now time.Time) ([]string, error) {
+	return transitionEvents(ctx, tx, model.EventStatusDraft, model.EventStatusScheduled, `opens_at > $1`, now)
+}
+
+// OpenDue opens scheduled events whose opens_at has passed and returns their
+// ids. Events with eligible voters lacking a value for the second factor stay
+// SCHEDULED, as EventService.Open refuses them too.
+func (r *EventRepo) OpenDue(ctx context.Context, tx *sql.Tx, now time.Time) ([]string, error) {
+	return transitionEvents(ctx, tx, model.EventStatusScheduled, model.EventStatusOpen, `opens_at <= $1 AND NOT EXISTS (
+			SELECT 1 FROM voters v WHERE v.event_id = events.id AND v.status = 'ELIGIBLE'
+			  AND ((events.second_factor = 'DOB' AND v.dob_hash IS NULL)
+			    OR (events.second_factor = 'PHONE_LAST4' AND v.phone_last4_hash IS NULL)))`, now)
+}
+
+// CloseDue closes open events whose closes_at has passed and returns their
+// ids.
+func (r *EventRepo) CloseDue(ctx context.Context, tx *sql.Tx, now time.Time) ([]string, error) {
+	return transitionEvents(ctx, tx, model.EventStatusOpen, model.EventStatusClosed, `closes_at <= $1`, now)
+}
+
+func transitionEvents(ctx context.Context, tx *sql.Tx, from, to model.EventStatus, due string, now time.Time) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`UPDATE events SET status = $2, updated_at = now()
+		 WHERE status = $3 AND `+due+`
+		 RETURNING id`,
+		now, string(to), string(from),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListArchivable returns the ids of LOCKED events not yet archived that were
+// locked before the cutoff.
+func (r *EventRepo) ListArchivable(ctx context.Context, lockedBefore time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id FROM events
+		 WHERE status = 'LOCKED' AND archived_at IS NULL AND locked_at < $1
+		 ORDER BY locked_at`,
+		lockedBefore,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *EventRepo) MarkArchived(ctx context.Context, tx *sql.Tx, id string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE events SET archived_at = now(), registration_open = false, updated_at = now() WHERE id = $1`, id,
+	)
+	return err
+}
+
+// DeleteDraft deletes a DRAFT or SCHEDULED event and everything under it. It
+// returns the number of rows deleted, so 0 means the event opened in the
+// meantime.
+func (r *EventRepo) DeleteDraft(ctx context.Context, tx *sql.Tx, id string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id = $1 AND status IN ('DRAFT', 'SCHEDULED')`, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *EventRepo) UpdatePackage(ctx context.Context, id string, pkg model.Package, maxSlates, maxVoters int) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE events SET package = $2, max_slates = $3, max_voters = $4, updated_at = now() WHERE id = $1`,
@@ -96,3 +243,21 @@ func (r *EventRepo) UpdatePackage(ctx context.Context, id string, pkg model.Pack
 	)
 	return err
 }
+
+// UpdateNIMPolicy changes how an event normalizes and validates NIMs.
+func (r *EventRepo) UpdateNIMPolicy(ctx context.Context, id, stripChars string, caseFold, trimZeros bool, pattern string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE events SET nim_strip_chars = $2, nim_case_fold = $3, nim_trim_zeros = $4, nim_pattern = $5, updated_at = now() WHERE id = $1`,
+		id, stripChars, caseFold, trimZeros, pattern,
+	)
+	return err
+}
+
+// UpdateSecondFactor sets or, with nil, clears the event's second factor.
+func (r *EventRepo) UpdateSecondFactor(ctx context.Context, id string, factor *model.SecondFactor) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE events SET second_factor = $2, updated_at = now() WHERE id = $1`,
+		id, factor,
+	)
+	return err
+}
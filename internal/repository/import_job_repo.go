@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+type ImportJobRepo struct {
+	db *sql.DB
+}
+
+func NewImportJobRepo(db *sql.DB) *ImportJobRepo {
+	return &ImportJobRepo{db: db}
+}
+
+const importJobColumns = `id, event_id, created_by, mode, disable_missing, file_name, status, total_rows, processed_rows,
+		 rejected, COALESCE(result, 'null'), error, created_at, started_at, finished_at`
+
+func scanImportJob(s rowScanner, j *model.ImportJob) error {
+	return s.Scan(&j.ID, &j.EventID, &j.CreatedBy, &j.Mode, &j.DisableMissing, &j.FileName, &j.Status, &j.TotalRows, &j.ProcessedRows,
+		&j.Rejected, &j.Result, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt)
+}
+
+// ImportJobFile is the upload a job imports.
+type ImportJobFile struct {
+	Name  string
+	XLSX  bool
+	Sheet string
+	Data  []byte
+}
+
+// Create queues a job for the event.
+func (r *ImportJobRepo) Create(ctx context.Context, eventID, userID string, mode model.ImportMode, disableMissing bool, file ImportJobFile) (*model.ImportJob, error) {
+	var j model.ImportJob
+	err := scanImportJob(r.db.QueryRowContext(ctx,
+		`INSERT INTO import_jobs (event_id, created_by, mode, disable_missing, file_name, is_xlsx, sheet, file_data)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+		 RETURNING `+importJobColumns,
+		eventID, userID, string(mode), disableMissing, file.Name, file.XLSX, file.Sheet, file.Data,
+	), &j)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *ImportJobRepo) GetByID(ctx context.Context, id string) (*model.ImportJob, error) {
+	var j model.ImportJob
+	err := scanImportJob(r.db.QueryRowContext(ctx,
+		`SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1`, id,
+	), &j)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *ImportJobRepo) ListByEvent(ctx context.Context, eventID string) ([]model.ImportJob, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+importJobColumns+` FROM import_jobs WHERE event_id = $1 ORDER BY created_at DESC`,
+		eventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.ImportJob
+	for rows.Next() {
+		var j model.ImportJob
+		if err := scanImportJob(rows, &j); err != nil {
+			return nil, err
+		}
+		result = append(result, j)
+	}
+	return result, rows.Err()
+}
+
+// ClaimNext marks the event's oldest QUEUED job RUNNING, leased to workerID,
+// and returns it with its file. It returns sql.ErrNoRows when nothing is
+// queued or another job of the event is already running.
+func (r *ImportJobRepo) ClaimNext(ctx context.Context, eventID, workerID string) (*model.ImportJob, *ImportJobFile, error) {
+	var j model.ImportJob
+	var f ImportJobFile
+	var sheet sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE import_jobs SET status = 'RUNNING', started_at = now(), worker_id = $2, heartbeat_at = now()
+		 WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE event_id = $1 AND status = 'QUEUED'
+			  AND NOT EXISTS (SELECT 1 FROM import_jobs WHERE event_id = $1 AND status = 'RUNNING')
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+importJobColumns+`, file_name, is_xlsx, sheet, file_data`,
+		eventID, workerID,
+	).Scan(&j.ID, &j.EventID, &j.CreatedBy, &j.Mode, &j.DisableMissing, &j.FileName, &j.Status, &j.TotalRows, &j.ProcessedRows,
+		&j.Rejected, &j.Result, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt,
+		&f.Name, &f.XLSX, &sheet, &f.Data)
+	if err != nil {
+		// A concurrent claim can trip the one-running-job index.
+		if strings.Contains(err.Error(), "uq_import_jobs_event_running") {
+			return nil, nil, sql.ErrNoRows
+		}
+		return nil, nil, err
+	}
+	f.Sheet = sheet.String
+	return &j, &f, nil
+}
+
+// Heartbeat renews workerID's lease on a RUNNING job. It returns false when
+// the lease was lost, e.g. requeued after expiring.
+func (r *ImportJobRepo) Heartbeat(ctx context.Context, id, workerID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE import_jobs SET heartbeat_at = now() WHERE id = $1 AND worker_id = $2 AND status = 'RUNNING'`,
+		id, workerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// SetPlanned records the job's row count and the rejects found while parsing.
+func (r *ImportJobRepo) SetPlanned(ctx context.Context, id, workerID string, totalRows int, rejected []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE import_jobs SET total_rows = $3, rejected = $4::jsonb WHERE id = $1 AND worker_id = $2`,
+		id, workerID, totalRows, string(rejected),
+	)
+	return err
+}
+
+func (r *ImportJobRepo) SetProgress(ctx context.Context, id, workerID string, processedRows int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE import_jobs SET processed_rows = $3 WHERE id = $1 AND worker_id = $2`,
+		id, workerID, processedRows,
+	)
+	return err
+}
+
+// MarkSucceeded stores the final result and rejects; the file is dropped.
+func (r *ImportJobRepo) MarkSucceeded(ctx context.Context, id, workerID string, rejected, result []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE import_jobs SET status = 'SUCCEEDED', processed_rows = total_rows, rejected = $3::jsonb, result = $4::jsonb,
+			file_data = '', finished_at = now(), worker_id = NULL, heartbeat_at = NULL
+		 WHERE id = $1 AND worker_id = $2`,
+		id, workerID, string(rejected), string(result),
+	)
+	return err
+}
+
+// MarkFailed records why a job failed. Nothing it inserted is kept, so the
+// progress is reset.
+func (r *ImportJobRepo) MarkFailed(ctx context.Context, id, workerID, reason string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE import_jobs SET status = 'FAILED', processed_rows = 0, error = $3, file_data = '', finished_at = now(),
+			worker_id = NULL, heartbeat_at = NULL
+		 WHERE id = $1 AND worker_id = $2`,
+		id, workerID, reason,
+	)
+	return err
+}
+
+// RequeueExpired puts RUNNING jobs whose lease has not been renewed within ttl
+// back in the queue: their worker stopped, and their inserts were rolled back
+// with its transaction, so they start over. Jobs other instances are still
+// running keep their lease.
+func (r *ImportJobRepo) RequeueExpired(ctx context.Context, ttl time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE import_jobs SET status = 'QUEUED', processed_rows = 0, started_at = NULL, worker_id = NULL, heartbeat_at = NULL
+		 WHERE status = 'RUNNING' AND (heartbeat_at IS NULL OR heartbeat_at < now() - make_interval(secs => $1))`,
+		ttl.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListQueuedEvents returns the events that have QUEUED jobs.
+func (r *ImportJobRepo) ListQueuedEvents(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT event_id FROM import_jobs WHERE status = 'QUEUED'`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgeEvent deletes the event's import jobs with their uploaded files, for archival.
+func (r *ImportJobRepo) PurgeEvent(ctx context.Context, tx *sql.Tx, eventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM import_jobs WHERE event_id = $1`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
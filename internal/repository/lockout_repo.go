@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+type LockoutRepo struct {
+	db *sql.DB
+}
+
+func NewLockoutRepo(db *sql.DB) *LockoutRepo {
+	return &LockoutRepo{db: db}
+}
+
+const lockoutColumns = `id, event_id, kind, subject, failures, lockouts, locked_until, last_failed_at, created_at`
+
+func scanLockout(s rowScanner, l *model.VoteLockout) error {
+	return s.Scan(&l.ID, &l.EventID, &l.Kind, &l.Subject, &l.Failures, &l.Lockouts, &l.LockedUntil, &l.LastFailedAt, &l.CreatedAt)
+}
+
+// LockedUntil returns the latest active lock among the given token and NIM, or
+// nil when neither is locked.
+func (r *LockoutRepo) LockedUntil(ctx context.Context, eventID, token, nim string) (*time.Time, error) {
+	var until sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT MAX(locked_until) FROM vote_lockouts
+		 WHERE event_id = $1 AND locked_until > now()
+		   AND ((kind = 'TOKEN' AND subject = $2) OR (kind = 'NIM' AND subject = $3))`,
+		eventID, token, nim,
+	).Scan(&until)
+	if err != nil || !until.Valid {
+		return nil, err
+	}
+	return &until.Time, nil
+}
+
+// RecordFailure counts a failed attempt. Failures older than window no longer
+// count towards the threshold. A TOKEN subject is only recorded when the token
+// exists for the event, so guessed tokens cannot grow the table; it returns
+// nil, nil for those.
+func (r *LockoutRepo) RecordFailure(ctx context.Context, eventID string, kind model.LockoutKind, subject string, window time.Duration) (*model.VoteLockout, error) {
+	var l model.VoteLockout
+	err := scanLockout(r.db.QueryRowContext(ctx,
+		`INSERT INTO vote_lockouts (event_id, kind, subject, failures)
+		 SELECT $1, $2, $3, 1
+		 WHERE $2 <> 'TOKEN' OR EXISTS (SELECT 1 FROM voter_tokens WHERE event_id = $1 AND token = $3)
+		 ON CONFLICT (event_id, kind, subject) DO UPDATE SET
+			failures = CASE WHEN vote_lockouts.last_failed_at < now() - make_interval(secs => $4)
+				THEN 1 ELSE vote_lockouts.failures + 1 END,
+			last_failed_at = now()
+		 RETURNING `+lockoutColumns,
+		eventID, string(kind), subject, window.Seconds(),
+	), &l)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// PruneStale deletes records that were never locked and whose last failure is
+// older than window, so their failures no longer count. Records of subjects
+// that were locked are kept for the backoff history.
+func (r *LockoutRepo) PruneStale(ctx context.Context, window time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM vote_lockouts
+		 WHERE lockouts = 0 AND last_failed_at < now() - make_interval(secs => $1)`,
+		window.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Lock starts a lockout period and resets the failure counter.
+func (r *LockoutRepo) Lock(ctx context.Context, id string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE vote_lockouts SET failures = 0, lockouts = lockouts + 1, locked_until = $2 WHERE id = $1`,
+		id, until,
+	)
+	return err
+}
+
+// ResetFailures clears the failure counters of a token and NIM after a
+// successful attempt. Past lockouts are kept so repeat offenders back off longer.
+func (r *LockoutRepo) ResetFailures(ctx context.Context, eventID, token, nim string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE vote_lockouts SET failures = 0
+		 WHERE event_id = $1 AND failures > 0
+		   AND ((kind = 'TOKEN' AND subject = $2) OR (kind = 'NIM' AND subject = $3))`,
+		eventID, token, nim,
+	)
+	return err
+}
+
+// ListByEvent returns an event's lockout records, most recent failure first.
+// With activeOnly only currently locked subjects are returned.
+func (r *LockoutRepo) ListByEvent(ctx context.Context, eventID string, activeOnly bool) ([]model.VoteLockout, error) {
+	query := `SELECT ` + lockoutColumns + ` FROM vote_lockouts WHERE event_id = $1`
+	if activeOnly {
+		query += ` AND locked_until > now()`
+	}
+	query += ` ORDER BY last_failed_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.VoteLockout
+	for rows.Next() {
+		var l model.VoteLockout
+		if err := scanLockout(rows, &l); err != nil {
+			return nil, err
+		}
+		result = append(result, l)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes a lockout record, clearing both the lock and its history.
+func (r *LockoutRepo) Delete(ctx context.Context, eventID, id string) (*model.VoteLockout, error) {
+	var l model.VoteLockout
+	err := scanLockout(r.db.QueryRowContext(ctx,
+		`DELETE FROM vote_lockouts WHERE id = $1 AND event_id = $2 RETURNING `+lockoutColumns,
+		id, eventID,
+	), &l)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// PurgeEvent deletes the event's lockouts, which are keyed on tokens and NIMs, for archival.
+func (r *LockoutRepo) PurgeEvent(ctx context.Context, tx *sql.Tx, eventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM vote_lockouts WHERE event_id = $1`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -67,3 +67,12 @@ func (r *OrderRepo) UpdateStatus(ctx context.Context, id string, status model.Or
 	)
 	return err
 }
+
+// HasPaid reports whether the event has a paid order.
+func (r *OrderRepo) HasPaid(ctx context.Context, eventID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM orders WHERE event_id = $1 AND status = 'PAID')`, eventID,
+	).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+type ProxyRepo struct {
+	db *sql.DB
+}
+
+func NewProxyRepo(db *sql.DB) *ProxyRepo {
+	return &ProxyRepo{db: db}
+}
+
+const proxyColumns = `id, event_id, grantor_voter_id, holder_voter_id, status, note, created_by, created_at, used_at, revoked_at`
+
+func scanProxy(s rowScanner, p *model.Proxy) error {
+	return s.Scan(&p.ID, &p.EventID, &p.GrantorVoterID, &p.HolderVoterID, &p.Status, &p.Note, &p.CreatedBy, &p.CreatedAt, &p.UsedAt, &p.RevokedAt)
+}
+
+// ProxyListRow is a proxy with both members' names for the admin listing.
+type ProxyListRow struct {
+	model.Proxy
+	GrantorName     string `json:"grantor_name"`
+	GrantorNIM      string `json:"grantor_nim"`
+	GrantorHasVoted bool   `json:"grantor_has_voted"`
+	HolderName      string `json:"holder_name"`
+	HolderNIM       string `json:"holder_nim"`
+}
+
+// OpenProxy is an unused proxy offered in the holder's voting session.
+type OpenProxy struct {
+	ProxyID   string
+	FullName  string
+	ClassName *string
+}
+
+func (r *ProxyRepo) CreateInTx(ctx context.Context, tx *sql.Tx, eventID, grantorID, holderID, note, createdBy string) (*model.Proxy, error) {
+	var p model.Proxy
+	err := scanProxy(tx.QueryRowContext(ctx,
+		`INSERT INTO proxies (event_id, grantor_voter_id, holder_voter_id, note, created_by)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		 RETURNING `+proxyColumns,
+		eventID, grantorID, holderID, note, createdBy,
+	), &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// HasLiveAsGrantor reports whether the voter has delegated their vote and the
+// proxy was not revoked.
+func (r *ProxyRepo) HasLiveAsGrantor(ctx context.Context, tx *sql.Tx, voterID string) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM proxies WHERE grantor_voter_id = $1 AND status <> 'REVOKED')`,
+		voterID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// CountLiveByHolder counts the non-revoked proxies a voter holds.
+func (r *ProxyRepo) CountLiveByHolder(ctx context.Context, tx *sql.Tx, voterID string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM proxies WHERE holder_voter_id = $1 AND status <> 'REVOKED'`,
+		voterID,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *ProxyRepo) LockForUpdate(ctx context.Context, tx *sql.Tx, id string) (*model.Proxy, error) {
+	var p model.Proxy
+	err := scanProxy(tx.QueryRowContext(ctx,
+		`SELECT `+proxyColumns+` FROM proxies WHERE id = $1 FOR UPDATE`, id,
+	), &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProxyRepo) MarkUsed(ctx context.Context, tx *sql.Tx, id string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE proxies SET status = 'USED', used_at = now() WHERE id = $1`, id,
+	)
+	return err
+}
+
+// Revoke cancels an ACTIVE proxy. It returns the number of rows changed.
+func (r *ProxyRepo) Revoke(ctx context.Context, eventID, id string) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE proxies SET status = 'REVOKED', revoked_at = now()
+		 WHERE id = $1 AND event_id = $2 AND status = 'ACTIVE'`,
+		id, eventID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *ProxyRepo) ListByEvent(ctx context.Context, eventID string) ([]ProxyListRow, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT p.id, p.event_id, p.grantor_voter_id, p.holder_voter_id, p.status, p.note, p.created_by, p.created_at, p.used_at, p.revoked_at,
+		        g.full_name, g.nim_raw, g.has_voted, h.full_name, h.nim_raw
+		 FROM proxies p
+		 JOIN voters g ON g.id = p.grantor_voter_id
+		 JOIN voters h ON h.id = p.holder_voter_id
+		 WHERE p.event_id = $1
+		 ORDER BY p.created_at DESC`,
+		eventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []ProxyListRow
+	for rows.Next() {
+		var row ProxyListRow
+		p := &row.Proxy
+		if err := rows.Scan(&p.ID, &p.EventID, &p.GrantorVoterID, &p.HolderVoterID, &p.Status, &p.Note, &p.CreatedBy, &p.CreatedAt, &p.UsedAt, &p.RevokedAt,
+			&row.GrantorName, &row.GrantorNIM, &row.GrantorHasVoted, &row.HolderName, &row.HolderNIM); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// ListOpenByHolder returns the ACTIVE proxies a voter holds whose grantor is
+// eligible and has not voted yet.
+func (r *ProxyRepo) ListOpenByHolder(ctx context.Context, holderID string) ([]OpenProxy, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT p.id, g.full_name, g.class_name
+		 FROM proxies p
+		 JOIN voters g ON g.id = p.grantor_voter_id
+		 WHERE p.holder_voter_id = $1 AND p.status = 'ACTIVE'
+		   AND g.status = 'ELIGIBLE' AND g.has_voted = false
+		 ORDER BY g.full_name`,
+		holderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []OpenProxy
+	for rows.Next() {
+		var p OpenProxy
+		if err := rows.Scan(&p.ProxyID, &p.FullName, &p.ClassName); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
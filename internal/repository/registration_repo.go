@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/lib/pq"
+)
+
+type RegistrationRepo struct {
+	db *sql.DB
+}
+
+func NewRegistrationRepo(db *sql.DB) *RegistrationRepo {
+	return &RegistrationRepo{db: db}
+}
+
+const registrationColumns = `id, event_id, full_name, nim_raw, nim_normalized, class_name, email, phone, dob_hash, status,
+		 reject_reason, voter_id, reviewed_by, reviewed_at, created_at`
+
+func scanRegistration(s rowScanner, reg *model.VoterRegistration) error {
+	return s.Scan(&reg.ID, &reg.EventID, &reg.FullName, &reg.NIMRaw, &reg.NIMNormalized, &reg.ClassName, &reg.Email, &reg.Phone, &reg.DOBHash, &reg.Status,
+		&reg.RejectReason, &reg.VoterID, &reg.ReviewedBy, &reg.ReviewedAt, &reg.CreatedAt)
+}
+
+// Create queues a PENDING registration. It returns sql.ErrNoRows when the NIM
+// already has a pending entry for the event.
+func (r *RegistrationRepo) Create(ctx context.Context, eventID string, row VoterInsertRow) (*model.VoterRegistration, error) {
+	var reg model.VoterRegistration
+	err := scanRegistration(r.db.QueryRowContext(ctx,
+		`INSERT INTO voter_registrations (event_id, full_name, nim_raw, nim_normalized, class_name, email, phone, dob_hash)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''))
+		 ON CONFLICT (event_id, nim_normalized) WHERE status = 'PENDING' DO NOTHING
+		 RETURNING `+registrationColumns,
+		eventID, row.FullName, row.NIMRaw, row.NIMNormalized, row.ClassName, row.Email, row.Phone, row.DOBHash,
+	), &reg)
+	if err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+func (r *RegistrationRepo) ListByEvent(ctx context.Context, eventID string, status string) ([]model.VoterRegistration, error) {
+	query := `SELECT ` + registrationColumns + ` FROM voter_registrations WHERE event_id = $1`
+	args := []interface{}{eventID}
+	if status != "" {
+		query += ` AND status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.VoterRegistration
+	for rows.Next() {
+		var reg model.VoterRegistration
+		if err := scanRegistration(rows, &reg); err != nil {
+			return nil, err
+		}
+		result = append(result, reg)
+	}
+	return result, rows.Err()
+}
+
+// LockPending locks the PENDING registrations among ids that belong to the
+// event. Entries that are missing or already reviewed are simply not returned.
+func (r *RegistrationRepo) LockPending(ctx context.Context, tx *sql.Tx, eventID string, ids []string) ([]model.VoterRegistration, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+registrationColumns+` FROM voter_registrations
+		 WHERE event_id = $1 AND id = ANY($2::uuid[]) AND status = 'PENDING'
+		 ORDER BY created_at
+		 FOR UPDATE`,
+		eventID, pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.VoterRegistration
+	for rows.Next() {
+		var reg model.VoterRegistration
+		if err := scanRegistration(rows, &reg); err != nil {
+			return nil, err
+		}
+		result = append(result, reg)
+	}
+	return result, rows.Err()
+}
+
+func (r *RegistrationRepo) MarkApproved(ctx context.Context, tx *sql.Tx, id, voterID, reviewerID string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE voter_registrations SET status = 'APPROVED', voter_id = $2, reviewed_by = $3, reviewed_at = now()
+		 WHERE id = $1`,
+		id, voterID, reviewerID,
+	)
+	return err
+}
+
+func (r *RegistrationRepo) MarkRejected(ctx context.Context, tx *sql.Tx, id, reviewerID, reason string) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE voter_registrations SET status = 'REJECTED', reject_reason = NULLIF($3, ''), reviewed_by = $2, reviewed_at = now()
+		 WHERE id = $1`,
+		id, reviewerID, reason,
+	)
+	return err
+}
+
+// PurgeEvent deletes the event's self-registration applications, for archival.
+func (r *RegistrationRepo) PurgeEvent(ctx context.Context, tx *sql.Tx, eventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM voter_registrations WHERE event_id = $1`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -170,3 +170,30 @@ func (r *SlateRepo) DeleteMember(ctx context.Context, id string) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM slate_members WHERE id = $1`, id)
 	return err
 }
+
+// CopyInTx recreates slates and their members on another event.
+func (r *SlateRepo) CopyInTx(ctx context.Context, tx *sql.Tx, eventID string, slates []model.Slate) error {
+	for _, s := range slates {
+		var slateID string
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO slates (event_id, number, name, vision, mission, photo_url)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING id`,
+			eventID, s.Number, s.Name, s.Vision, s.Mission, s.PhotoURL,
+		).Scan(&slateID)
+		if err != nil {
+			return err
+		}
+		for _, m := range s.Members {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO slate_members (slate_id, role, full_name, photo_url, bio, sort_order)
+				 VALUES ($1, $2, $3, $4, $5, $6)`,
+				slateID, m.Role, m.FullName, m.PhotoURL, m.Bio, m.SortOrder,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/amard/pemilo-golang/internal/model"
+)
+
+type TemplateRepo struct {
+	db *sql.DB
+}
+
+func NewTemplateRepo(db *sql.DB) *TemplateRepo {
+	return &TemplateRepo{db: db}
+}
+
+const templateColumns = `id, owner_user_id, name, source_event_id, title, description, settings, slates, created_at`
+
+func scanTemplate(s rowScanner, t *model.EventTemplate) error {
+	var settings, slates []byte
+	if err := s.Scan(&t.ID, &t.OwnerUserID, &t.Name, &t.SourceEventID, &t.Title, &t.Description, &settings, &slates, &t.CreatedAt); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(settings, &t.Settings); err != nil {
+		return err
+	}
+	return json.Unmarshal(slates, &t.Slates)
+}
+
+// Create stores a template. It returns an error mentioning
+// uq_event_templates_owner_name when the owner already has one by that name.
+func (r *TemplateRepo) Create(ctx context.Context, t *model.EventTemplate) (*model.EventTemplate, error) {
+	settings, err := json.Marshal(t.Settings)
+	if err != nil {
+		return nil, err
+	}
+	slates := t.Slates
+	if slates == nil {
+		slates = []model.Slate{}
+	}
+	slatesJSON, err := json.Marshal(slates)
+	if err != nil {
+		return nil, err
+	}
+
+	var created model.EventTemplate
+	err = scanTemplate(r.db.QueryRowContext(ctx,
+		`INSERT INTO event_templates (owner_user_id, name, source_event_id, title, description, settings, slates)
+		 VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb)
+		 RETURNING `+templateColumns,
+		t.OwnerUserID, t.Name, t.SourceEventID, t.Title, t.Description, string(settings), string(slatesJSON),
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *TemplateRepo) GetByID(ctx context.Context, id string) (*model.EventTemplate, error) {
+	var t model.EventTemplate
+	err := scanTemplate(r.db.QueryRowContext(ctx,
+		`SELECT `+templateColumns+` FROM event_templates WHERE id = $1`, id,
+	), &t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TemplateRepo) ListByOwner(ctx context.Context, ownerUserID string) ([]model.EventTemplate, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+templateColumns+` FROM event_templates WHERE owner_user_id = $1 ORDER BY name`,
+		ownerUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []model.EventTemplate
+	for rows.Next() {
+		var t model.EventTemplate
+		if err := scanTemplate(rows, &t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (r *TemplateRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM event_templates WHERE id = $1`, id)
+	return err
+}
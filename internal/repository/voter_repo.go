@@ -3,11 +3,14 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/amard/pemilo-golang/internal/dto"
 	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/lib/pq"
 )
 
 type VoterRepo struct {
@@ -18,32 +21,274 @@ func NewVoterRepo(db *sql.DB) *VoterRepo {
 	return &VoterRepo{db: db}
 }
 
-func (r *VoterRepo) BulkInsert(ctx context.Context, eventID string, rows []struct {
+const voterColumns = `id, event_id, full_name, nim_raw, nim_normalized, class_name, email, phone, dob_hash, phone_last4_hash, attributes, status, has_voted, voted_at, created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVoter(s rowScanner, v *model.Voter) error {
+	var attrs []byte
+	if err := s.Scan(&v.ID, &v.EventID, &v.FullName, &v.NIMRaw, &v.NIMNormalized, &v.ClassName, &v.Email, &v.Phone, &v.DOBHash, &v.PhoneLast4Hash, &attrs, &v.Status, &v.HasVoted, &v.VotedAt, &v.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(attrs, &v.Attributes)
+}
+
+// attributesJSON encodes custom attribute values for a JSONB column.
+func attributesJSON(attrs map[string]string) string {
+	if len(attrs) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(attrs)
+	return string(b)
+}
+
+// VoterInsertRow is a single parsed roster row ready for insertion.
+type VoterInsertRow struct {
+	Row           int // source line in an imported file, for rejections
 	FullName      string
 	NIMRaw        string
 	NIMNormalized string
 	ClassName     string
-}) (int, []dto.ImportReject, error) {
-	imported := 0
-	var rejected []dto.ImportReject
+	Email         string
+	Phone         string
+	DOBHash       string
+	// PhoneLast4Hash is set by the service whenever Phone is.
+	PhoneLast4Hash string
+	Attributes     map[string]string
+}
 
+// BulkInsert adds rows to the event's roster with a single statement inside
+// the caller's transaction. Rows whose NIM is already on the roster are skipped
+// and reported as rejects; any other failure aborts the whole insert.
+func (r *VoterRepo) BulkInsert(ctx context.Context, tx *sql.Tx, eventID string, rows []VoterInsertRow) (int, []dto.ImportReject, error) {
+	if len(rows) == 0 {
+		return 0, nil, nil
+	}
+
+	n := len(rows)
+	fullNames, nimRaws, nimNorms := make([]string, n), make([]string, n), make([]string, n)
+	classNames, emails, phones, dobHashes := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	phoneHashes, attributes := make([]string, n), make([]string, n)
 	for i, row := range rows {
-		_, err := r.db.ExecContext(ctx,
-			`INSERT INTO voters (event_id, full_name, nim_raw, nim_normalized, class_name)
-			 VALUES ($1, $2, $3, $4, NULLIF($5, ''))`,
-			eventID, row.FullName, row.NIMRaw, row.NIMNormalized, row.ClassName,
-		)
-		if err != nil {
-			if strings.Contains(err.Error(), "uq_voters_event_nim") {
-				rejected = append(rejected, dto.ImportReject{Row: i + 2, Reason: "duplicate nim in event"})
-			} else {
-				rejected = append(rejected, dto.ImportReject{Row: i + 2, Reason: err.Error()})
-			}
-			continue
+		fullNames[i], nimRaws[i], nimNorms[i] = row.FullName, row.NIMRaw, row.NIMNormalized
+		classNames[i], emails[i], phones[i], dobHashes[i] = row.ClassName, row.Email, row.Phone, row.DOBHash
+		phoneHashes[i], attributes[i] = row.PhoneLast4Hash, attributesJSON(row.Attributes)
+	}
+
+	returned, err := tx.QueryContext(ctx,
+		`INSERT INTO voters (event_id, full_name, nim_raw, nim_normalized, class_name, email, phone, dob_hash, phone_last4_hash, attributes)
+		 SELECT $1, r.full_name, r.nim_raw, r.nim_normalized, NULLIF(r.class_name, ''), NULLIF(r.email, ''), NULLIF(r.phone, ''),
+		        NULLIF(r.dob_hash, ''), NULLIF(r.phone_last4_hash, ''), r.attributes::jsonb
+		 FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[], $9::text[], $10::text[])
+		   AS r(full_name, nim_raw, nim_normalized, class_name, email, phone, dob_hash, phone_last4_hash, attributes)
+		 ON CONFLICT ON CONSTRAINT uq_voters_event_nim DO NOTHING
+		 RETURNING nim_normalized`,
+		eventID, pq.Array(fullNames), pq.Array(nimRaws), pq.Array(nimNorms),
+		pq.Array(classNames), pq.Array(emails), pq.Array(phones), pq.Array(dobHashes), pq.Array(phoneHashes), pq.Array(attributes),
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer returned.Close()
+
+	inserted := make(map[string]bool, n)
+	for returned.Next() {
+		var nim string
+		if err := returned.Scan(&nim); err != nil {
+			return 0, nil, err
 		}
-		imported++
+		inserted[nim] = true
+	}
+	if err := returned.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var rejected []dto.ImportReject
+	for _, row := range rows {
+		if !inserted[row.NIMNormalized] {
+			rejected = append(rejected, dto.ImportReject{Row: row.Row, Reason: "duplicate nim in event"})
+		}
+	}
+	return len(inserted), rejected, nil
+}
+
+// CreateInTx inserts a single voter within a transaction. It returns
+// sql.ErrNoRows when the NIM is already on the event's roster.
+func (r *VoterRepo) CreateInTx(ctx context.Context, tx *sql.Tx, eventID string, row VoterInsertRow) (*model.Voter, error) {
+	var v model.Voter
+	err := scanVoter(tx.QueryRowContext(ctx,
+		`INSERT INTO voters (event_id, full_name, nim_raw, nim_normalized, class_name, email, phone, dob_hash, phone_last4_hash, attributes)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10::jsonb)
+		 ON CONFLICT ON CONSTRAINT uq_voters_event_nim DO NOTHING
+		 RETURNING `+voterColumns,
+		eventID, row.FullName, row.NIMRaw, row.NIMNormalized, row.ClassName, row.Email, row.Phone, row.DOBHash, row.PhoneLast4Hash, attributesJSON(row.Attributes),
+	), &v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// UpdateDetails replaces a voter's roster fields and attributes. An empty
+// DOBHash keeps the stored one; the phone hash follows the phone.
+func (r *VoterRepo) UpdateDetails(ctx context.Context, id string, row VoterInsertRow) (*model.Voter, error) {
+	var v model.Voter
+	err := scanVoter(r.db.QueryRowContext(ctx,
+		`UPDATE voters SET full_name = $2, nim_raw = $3, nim_normalized = $4, class_name = NULLIF($5, ''),
+			email = NULLIF($6, ''), phone = NULLIF($7, ''), dob_hash = COALESCE(NULLIF($8, ''), dob_hash),
+			phone_last4_hash = NULLIF($9, ''), attributes = $10::jsonb
+		 WHERE id = $1
+		 RETURNING `+voterColumns,
+		id, row.FullName, row.NIMRaw, row.NIMNormalized, row.ClassName, row.Email, row.Phone, row.DOBHash, row.PhoneLast4Hash, attributesJSON(row.Attributes),
+	), &v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *VoterRepo) SetStatus(ctx context.Context, id string, status model.VoterStatus) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE voters SET status = $2 WHERE id = $1`, id, string(status))
+	return err
+}
+
+// UpdateNameClass sets a voter's name, class and attributes during a roster
+// sync.
+// Voters who have voted are left alone; it returns the number of rows changed.
+func (r *VoterRepo) UpdateNameClass(ctx context.Context, tx *sql.Tx, id, fullName, className string, attributes map[string]string) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE voters SET full_name = $2, class_name = NULLIF($3, ''), attributes = $4::jsonb WHERE id = $1 AND has_voted = false`,
+		id, fullName, className, attributesJSON(attributes),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// missingVoters matches the event's eligible voters who have not voted and
+// whose NIM is not in keep.
+const missingVoters = `event_id = $1 AND status = 'ELIGIBLE' AND has_voted = false AND NOT (nim_normalized = ANY($2))`
+
+// DisableMissing disables the event's eligible voters who have not voted and
+// whose NIM is not in keep. It returns the number of voters disabled.
+func (r *VoterRepo) DisableMissing(ctx context.Context, tx *sql.Tx, eventID string, keep []string) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE voters SET status = 'DISABLED' WHERE `+missingVoters,
+		eventID, pq.Array(keep),
+	)
+	if err != nil {
+		return 0, err
 	}
-	return imported, rejected, nil
+	return result.RowsAffected()
+}
+
+// CountMissing counts the voters DisableMissing would disable.
+func (r *VoterRepo) CountMissing(ctx context.Context, eventID string, keep []string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM voters WHERE `+missingVoters,
+		eventID, pq.Array(keep),
+	).Scan(&count)
+	return count, err
+}
+
+// CopyEligible copies the eligible voters of one event onto another as fresh
+// voters who have not voted. DOB and phone hashes are keyed per event and are
+// not copied. It returns the number of voters copied.
+func (r *VoterRepo) CopyEligible(ctx context.Context, tx *sql.Tx, fromEventID, toEventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO voters (event_id, full_name, nim_raw, nim_normalized, class_name, email, phone, attributes)
+		 SELECT $2, full_name, nim_raw, nim_normalized, class_name, email, phone, attributes
+		 FROM voters WHERE event_id = $1 AND status = 'ELIGIBLE'`,
+		fromEventID, toEventID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountMissingSecondFactor counts the event's eligible voters that lack the
+// stored hash the given second factor is checked against.
+func (r *VoterRepo) CountMissingSecondFactor(ctx context.Context, tx *sql.Tx, eventID string, factor model.SecondFactor) (int, error) {
+	column := "dob_hash"
+	if factor == model.SecondFactorPhoneLast4 {
+		column = "phone_last4_hash"
+	}
+	var count int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM voters WHERE event_id = $1 AND status = 'ELIGIBLE' AND `+column+` IS NULL`,
+		eventID,
+	).Scan(&count)
+	return count, err
+}
+
+// PhonesWithoutLast4Hash returns the voters that have a phone but no
+// phone_last4_hash, with only ID, EventID and Phone set. An empty eventID
+// covers every event.
+func (r *VoterRepo) PhonesWithoutLast4Hash(ctx context.Context, tx *sql.Tx, eventID string) ([]model.Voter, error) {
+	query := `SELECT id, event_id, phone FROM voters WHERE phone IS NOT NULL AND phone_last4_hash IS NULL`
+	var args []interface{}
+	if eventID != "" {
+		query += ` AND event_id = $1`
+		args = append(args, eventID)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var voters []model.Voter
+	for rows.Next() {
+		var v model.Voter
+		if err := rows.Scan(&v.ID, &v.EventID, &v.Phone); err != nil {
+			return nil, err
+		}
+		voters = append(voters, v)
+	}
+	return voters, rows.Err()
+}
+
+// SetPhoneLast4Hashes stores hashes[i] as the phone hash of voter ids[i].
+func (r *VoterRepo) SetPhoneLast4Hashes(ctx context.Context, tx *sql.Tx, ids, hashes []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		`UPDATE voters v SET phone_last4_hash = u.hash
+		 FROM unnest($1::uuid[], $2::text[]) AS u(id, hash)
+		 WHERE v.id = u.id`,
+		pq.Array(ids), pq.Array(hashes),
+	)
+	return err
+}
+
+// Delete removes a voter who has not voted. It returns the number of rows
+// deleted, so 0 means the voter voted in the meantime.
+func (r *VoterRepo) Delete(ctx context.Context, id string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM voters WHERE id = $1 AND has_voted = false`, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountEligibleInTx counts the event's ELIGIBLE voters, the ones CopyEligible copies.
+func (r *VoterRepo) CountEligibleInTx(ctx context.Context, tx *sql.Tx, eventID string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM voters WHERE event_id = $1 AND status = 'ELIGIBLE'`, eventID).Scan(&count)
+	return count, err
+}
+
+func (r *VoterRepo) CountByEventInTx(ctx context.Context, tx *sql.Tx, eventID string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM voters WHERE event_id = $1`, eventID).Scan(&count)
+	return count, err
 }
 
 func (r *VoterRepo) CountByEvent(ctx context.Context, eventID string) (int, error) {
@@ -52,7 +297,52 @@ func (r *VoterRepo) CountByEvent(ctx context.Context, eventID string) (int, erro
 	return count, err
 }
 
-func (r *VoterRepo) List(ctx context.Context, eventID string, params dto.VoterListParams) ([]model.Voter, int, error) {
+// voterSortKeys maps the list's sort fields to the expression sorted on. Each
+// has an index on (event_id, expression, id); see migration 019.
+var voterSortKeys = map[string]struct {
+	expr, cast string
+}{
+	"created_at": {"created_at", "timestamptz"},
+	"full_name":  {"full_name", "text"},
+	"nim":        {"nim_normalized", "text"},
+	"class_name": {"COALESCE(class_name, '')", "text"},
+	"voted_at":   {"COALESCE(voted_at, '-infinity'::timestamptz)", "timestamptz"},
+}
+
+// IsVoterSortField reports whether List can sort on field.
+func IsVoterSortField(field string) bool {
+	_, ok := voterSortKeys[field]
+	return ok
+}
+
+// VoterSortValue is v's key for the sort field, in the text form List expects
+// in a cursor.
+func VoterSortValue(v *model.Voter, field string) string {
+	switch field {
+	case "full_name":
+		return v.FullName
+	case "nim":
+		return v.NIMNormalized
+	case "class_name":
+		if v.ClassName == nil {
+			return ""
+		}
+		return *v.ClassName
+	case "voted_at":
+		if v.VotedAt == nil {
+			return "-infinity"
+		}
+		return v.VotedAt.Format(time.RFC3339Nano)
+	default:
+		return v.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// List returns one page of the event's voters ordered by params.Sort, with id
+// breaking ties. With params.After it seeks past that voter (keyset
+// pagination); otherwise Page is used as an offset. total is only counted
+// with params.WithTotal, and hasMore reports whether another page follows.
+func (r *VoterRepo) List(ctx context.Context, eventID string, params dto.VoterListParams) (voters []model.Voter, total *int, hasMore bool, err error) {
 	where := []string{"event_id = $1"}
 	args := []interface{}{eventID}
 	argIdx := 2
@@ -72,56 +362,118 @@ func (r *VoterRepo) List(ctx context.Context, eventID string, params dto.VoterLi
 		args = append(args, *params.HasVoted)
 		argIdx++
 	}
+	if len(params.Attributes) > 0 {
+		where = append(where, fmt.Sprintf("attributes @> $%d::jsonb", argIdx))
+		args = append(args, attributesJSON(params.Attributes))
+		argIdx++
+	}
 
-	whereClause := strings.Join(where, " AND ")
+	if params.WithTotal {
+		var count int
+		err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM voters WHERE `+strings.Join(where, " AND "), args...).Scan(&count)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		total = &count
+	}
 
-	// Count
-	var total int
-	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM voters WHERE `+whereClause, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+	key, ok := voterSortKeys[params.Sort]
+	if !ok {
+		key = voterSortKeys["created_at"]
+	}
+	dir, cmp := "ASC", ">"
+	if params.Desc {
+		dir, cmp = "DESC", "<"
 	}
 
-	// Page
 	limit := params.PerPage
 	if limit <= 0 {
 		limit = 20
 	}
-	offset := (params.Page - 1) * limit
-	if offset < 0 {
-		offset = 0
+	offset := 0
+	if params.After != nil {
+		where = append(where, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d::uuid)", key.expr, cmp, argIdx, key.cast, argIdx+1))
+		args = append(args, params.After.Value, params.After.ID)
+	} else if params.Page > 1 {
+		offset = (params.Page - 1) * limit
 	}
 
+	// One extra row tells whether there is a next page.
 	query := fmt.Sprintf(
-		`SELECT id, event_id, full_name, nim_raw, nim_normalized, class_name, status, has_voted, voted_at, created_at
-		 FROM voters WHERE %s ORDER BY created_at DESC LIMIT %d OFFSET %d`,
-		whereClause, limit, offset,
+		`SELECT %s FROM voters WHERE %s ORDER BY %s %s, id %s LIMIT %d OFFSET %d`,
+		voterColumns, strings.Join(where, " AND "), key.expr, dir, dir, limit+1, offset,
 	)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v model.Voter
+		if err := scanVoter(rows, &v); err != nil {
+			return nil, nil, false, err
+		}
+		voters = append(voters, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, err
+	}
+	if len(voters) > limit {
+		voters, hasMore = voters[:limit], true
+	}
+	return voters, total, hasMore, nil
+}
+
+func (r *VoterRepo) ExistsByEventAndNIM(ctx context.Context, eventID, nimNormalized string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM voters WHERE event_id = $1 AND nim_normalized = $2)`,
+		eventID, nimNormalized,
+	).Scan(&exists)
+	return exists, err
+}
+
+// ListByNIMs returns the event's voters whose normalized NIM is in nims.
+func (r *VoterRepo) ListByNIMs(ctx context.Context, eventID string, nims []string) ([]model.Voter, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+voterColumns+` FROM voters WHERE event_id = $1 AND nim_normalized = ANY($2)`,
+		eventID, pq.Array(nims),
+	)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
 	var voters []model.Voter
 	for rows.Next() {
 		var v model.Voter
-		if err := rows.Scan(&v.ID, &v.EventID, &v.FullName, &v.NIMRaw, &v.NIMNormalized, &v.ClassName, &v.Status, &v.HasVoted, &v.VotedAt, &v.CreatedAt); err != nil {
-			return nil, 0, err
+		if err := scanVoter(rows, &v); err != nil {
+			return nil, err
 		}
 		voters = append(voters, v)
 	}
-	return voters, total, rows.Err()
+	return voters, rows.Err()
+}
+
+func (r *VoterRepo) GetByID(ctx context.Context, id string) (*model.Voter, error) {
+	var v model.Voter
+	err := scanVoter(r.db.QueryRowContext(ctx,
+		`SELECT `+voterColumns+` FROM voters WHERE id = $1`, id,
+	), &v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
 }
 
 func (r *VoterRepo) GetByEventAndNIM(ctx context.Context, eventID, nimNormalized string) (*model.Voter, error) {
 	var v model.Voter
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, event_id, full_name, nim_raw, nim_normalized, class_name, status, has_voted, voted_at, created_at
-		 FROM voters WHERE event_id = $1 AND nim_normalized = $2`,
+	err := scanVoter(r.db.QueryRowContext(ctx,
+		`SELECT `+voterColumns+` FROM voters WHERE event_id = $1 AND nim_normalized = $2`,
 		eventID, nimNormalized,
-	).Scan(&v.ID, &v.EventID, &v.FullName, &v.NIMRaw, &v.NIMNormalized, &v.ClassName, &v.Status, &v.HasVoted, &v.VotedAt, &v.CreatedAt)
+	), &v)
 	if err != nil {
 		return nil, err
 	}
@@ -139,13 +491,18 @@ func (r *VoterRepo) MarkVoted(ctx context.Context, tx *sql.Tx, voterID string) (
 	return result.RowsAffected()
 }
 
-// GetVotersWithoutToken returns voters that don't have an ACTIVE token.
+// withoutTokenJoin selects eligible voters that have not voted and hold no live
+// (ACTIVE or USED) token — i.e. voters that may be issued a token. Voters whose
+// only tokens were revoked qualify again.
+const withoutTokenJoin = `FROM voters v
+		 LEFT JOIN voter_tokens vt ON v.id = vt.voter_id AND vt.status <> 'REVOKED'
+		 WHERE v.status = 'ELIGIBLE' AND v.has_voted = false AND vt.id IS NULL`
+
+// GetVotersWithoutToken returns voters that can be issued a token.
 func (r *VoterRepo) GetVotersWithoutToken(ctx context.Context, eventID string) ([]model.Voter, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT v.id, v.event_id, v.full_name, v.nim_raw, v.nim_normalized, v.class_name, v.status, v.has_voted, v.voted_at, v.created_at
-		 FROM voters v
-		 LEFT JOIN voter_tokens vt ON v.id = vt.voter_id AND vt.status = 'ACTIVE'
-		 WHERE v.event_id = $1 AND v.status = 'ELIGIBLE' AND vt.id IS NULL`,
+		`SELECT v.id, v.event_id, v.full_name, v.nim_raw, v.nim_normalized, v.class_name, v.email, v.phone, v.dob_hash, v.phone_last4_hash, v.attributes, v.status, v.has_voted, v.voted_at, v.created_at
+		 `+withoutTokenJoin+` AND v.event_id = $1`,
 		eventID,
 	)
 	if err != nil {
@@ -156,7 +513,7 @@ func (r *VoterRepo) GetVotersWithoutToken(ctx context.Context, eventID string) (
 	var voters []model.Voter
 	for rows.Next() {
 		var v model.Voter
-		if err := rows.Scan(&v.ID, &v.EventID, &v.FullName, &v.NIMRaw, &v.NIMNormalized, &v.ClassName, &v.Status, &v.HasVoted, &v.VotedAt, &v.CreatedAt); err != nil {
+		if err := scanVoter(rows, &v); err != nil {
 			return nil, err
 		}
 		voters = append(voters, v)
@@ -164,11 +521,22 @@ func (r *VoterRepo) GetVotersWithoutToken(ctx context.Context, eventID string) (
 	return voters, rows.Err()
 }
 
+// IsWithoutToken reports whether a voter can be issued a token, using the same
+// rules as GetVotersWithoutToken. Call it inside the transaction that issues
+// the token, after any revocation.
+func (r *VoterRepo) IsWithoutToken(ctx context.Context, tx *sql.Tx, voterID string) (bool, error) {
+	var ok bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 `+withoutTokenJoin+` AND v.id = $1)`,
+		voterID,
+	).Scan(&ok)
+	return ok, err
+}
+
 // GetAllVotersForExport returns all voters for turnout export.
 func (r *VoterRepo) GetAllVotersForExport(ctx context.Context, eventID string) ([]model.Voter, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, event_id, full_name, nim_raw, nim_normalized, class_name, status, has_voted, voted_at, created_at
-		 FROM voters WHERE event_id = $1 ORDER BY full_name`,
+		`SELECT `+voterColumns+` FROM voters WHERE event_id = $1 ORDER BY full_name`,
 		eventID,
 	)
 	if err != nil {
@@ -179,10 +547,27 @@ func (r *VoterRepo) GetAllVotersForExport(ctx context.Context, eventID string) (
 	var voters []model.Voter
 	for rows.Next() {
 		var v model.Voter
-		if err := rows.Scan(&v.ID, &v.EventID, &v.FullName, &v.NIMRaw, &v.NIMNormalized, &v.ClassName, &v.Status, &v.HasVoted, &v.VotedAt, &v.CreatedAt); err != nil {
+		if err := scanVoter(rows, &v); err != nil {
 			return nil, err
 		}
 		voters = append(voters, v)
 	}
 	return voters, rows.Err()
 }
+
+// Pseudonymize erases the event's voters' personal data: names are replaced,
+// the NIM becomes the voter id, and email, phone and both second-factor
+// hashes are cleared.
+// Class, attributes, status and voting state stay for the turnout breakdowns.
+func (r *VoterRepo) Pseudonymize(ctx context.Context, tx *sql.Tx, eventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE voters SET full_name = '[redacted]', nim_raw = id::text, nim_normalized = id::text,
+		        email = NULL, phone = NULL, dob_hash = NULL, phone_last4_hash = NULL
+		 WHERE event_id = $1`,
+		eventID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/util"
+	_ "github.com/lib/pq"
+)
+
+// benchImportRows matches the largest roster a PRO event can hold.
+const benchImportRows = 1500
+
+// openBenchDB connects to DATABASE_URL and migrates it, skipping the
+// benchmark when no database is configured.
+func openBenchDB(b *testing.B) *sql.DB {
+	b.Helper()
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		b.Skip("DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	if err := util.RunMigrations(db); err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+// benchEvent creates a throwaway owner and event, removed when b finishes.
+func benchEvent(b *testing.B, db *sql.DB) string {
+	b.Helper()
+	ctx := context.Background()
+	user, err := repository.NewUserRepo(db).Create(ctx, fmt.Sprintf("bench-%d@example.test", os.Getpid()), "x", "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	event, err := repository.NewEventRepo(db).Create(ctx, user.ID, "bench", nil, nil, nil, 12, benchImportRows, "PRO",
+		util.DefaultTokenPolicy.Length, util.DefaultTokenPolicy.Alphabet, util.DefaultTokenPolicy.CheckChar)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		db.Exec(`DELETE FROM events WHERE id = $1`, event.ID)
+		db.Exec(`DELETE FROM users WHERE id = $1`, user.ID)
+	})
+	return event.ID
+}
+
+func benchRows() []repository.VoterInsertRow {
+	rows := make([]repository.VoterInsertRow, benchImportRows)
+	for i := range rows {
+		nim := fmt.Sprintf("%08d", i+1)
+		rows[i] = repository.VoterInsertRow{
+			Row:           i + 2,
+			FullName:      fmt.Sprintf("Voter %d", i+1),
+			NIMRaw:        nim,
+			NIMNormalized: nim,
+			ClassName:     fmt.Sprintf("XII-%d", i%10),
+			Email:         fmt.Sprintf("voter%d@example.test", i+1),
+		}
+	}
+	return rows
+}
+
+// BenchmarkBulkInsert compares importing a full PRO roster through the
+// unnest batch insert against one INSERT per row. Every iteration rolls back,
+// so each starts from an empty roster.
+func BenchmarkBulkInsert(b *testing.B) {
+	db := openBenchDB(b)
+	eventID := benchEvent(b, db)
+	voterRepo := repository.NewVoterRepo(db)
+	rows := benchRows()
+	ctx := context.Background()
+
+	run := func(b *testing.B, insert func(*sql.Tx) error) {
+		for i := 0; i < b.N; i++ {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := insert(tx); err != nil {
+				tx.Rollback()
+				b.Fatal(err)
+			}
+			tx.Rollback()
+		}
+	}
+
+	b.Run("unnest", func(b *testing.B) {
+		run(b, func(tx *sql.Tx) error {
+			imported, _, err := voterRepo.BulkInsert(ctx, tx, eventID, rows)
+			if err == nil && imported != len(rows) {
+				err = fmt.Errorf("imported %d of %d rows", imported, len(rows))
+			}
+			return err
+		})
+	})
+
+	b.Run("per_row", func(b *testing.B) {
+		run(b, func(tx *sql.Tx) error {
+			for _, row := range rows {
+				if _, err := voterRepo.CreateInTx(ctx, tx, eventID, row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
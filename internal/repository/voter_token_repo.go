@@ -15,26 +15,55 @@ func NewVoterTokenRepo(db *sql.DB) *VoterTokenRepo {
 	return &VoterTokenRepo{db: db}
 }
 
+const voterTokenColumns = `id, event_id, voter_id, token, status, issued_at, used_at, revoked_at, revoke_reason`
+
+func scanVoterToken(s rowScanner, vt *model.VoterToken) error {
+	return s.Scan(&vt.ID, &vt.EventID, &vt.VoterID, &vt.Token, &vt.Status, &vt.IssuedAt, &vt.UsedAt, &vt.RevokedAt, &vt.RevokeReason)
+}
+
 func (r *VoterTokenRepo) Create(ctx context.Context, eventID, voterID, token string) (*model.VoterToken, error) {
 	var vt model.VoterToken
-	err := r.db.QueryRowContext(ctx,
+	err := scanVoterToken(r.db.QueryRowContext(ctx,
 		`INSERT INTO voter_tokens (event_id, voter_id, token) VALUES ($1, $2, $3)
-		 RETURNING id, event_id, voter_id, token, status, issued_at, used_at`,
+		 RETURNING `+voterTokenColumns,
 		eventID, voterID, token,
-	).Scan(&vt.ID, &vt.EventID, &vt.VoterID, &vt.Token, &vt.Status, &vt.IssuedAt, &vt.UsedAt)
+	), &vt)
 	if err != nil {
 		return nil, err
 	}
 	return &vt, nil
 }
 
+func (r *VoterTokenRepo) CountByEvent(ctx context.Context, eventID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM voter_tokens WHERE event_id = $1`, eventID).Scan(&count)
+	return count, err
+}
+
+func (r *VoterTokenRepo) CountByEventInTx(ctx context.Context, tx *sql.Tx, eventID string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM voter_tokens WHERE event_id = $1`, eventID).Scan(&count)
+	return count, err
+}
+
 func (r *VoterTokenRepo) GetByEventAndToken(ctx context.Context, eventID, token string) (*model.VoterToken, error) {
 	var vt model.VoterToken
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, event_id, voter_id, token, status, issued_at, used_at
-		 FROM voter_tokens WHERE event_id = $1 AND token = $2`,
+	err := scanVoterToken(r.db.QueryRowContext(ctx,
+		`SELECT `+voterTokenColumns+` FROM voter_tokens WHERE event_id = $1 AND token = $2`,
 		eventID, token,
-	).Scan(&vt.ID, &vt.EventID, &vt.VoterID, &vt.Token, &vt.Status, &vt.IssuedAt, &vt.UsedAt)
+	), &vt)
+	if err != nil {
+		return nil, err
+	}
+	return &vt, nil
+}
+
+func (r *VoterTokenRepo) GetByID(ctx context.Context, id string) (*model.VoterToken, error) {
+	var vt model.VoterToken
+	err := scanVoterToken(r.db.QueryRowContext(ctx,
+		`SELECT `+voterTokenColumns+` FROM voter_tokens WHERE id = $1`,
+		id,
+	), &vt)
 	if err != nil {
 		return nil, err
 	}
@@ -44,11 +73,10 @@ func (r *VoterTokenRepo) GetByEventAndToken(ctx context.Context, eventID, token
 // LockForUpdate acquires a row lock on the token within a transaction.
 func (r *VoterTokenRepo) LockForUpdate(ctx context.Context, tx *sql.Tx, eventID, token string) (*model.VoterToken, error) {
 	var vt model.VoterToken
-	err := tx.QueryRowContext(ctx,
-		`SELECT id, event_id, voter_id, token, status, issued_at, used_at
-		 FROM voter_tokens WHERE event_id = $1 AND token = $2 FOR UPDATE`,
+	err := scanVoterToken(tx.QueryRowContext(ctx,
+		`SELECT `+voterTokenColumns+` FROM voter_tokens WHERE event_id = $1 AND token = $2 FOR UPDATE`,
 		eventID, token,
-	).Scan(&vt.ID, &vt.EventID, &vt.VoterID, &vt.Token, &vt.Status, &vt.IssuedAt, &vt.UsedAt)
+	), &vt)
 	if err != nil {
 		return nil, err
 	}
@@ -64,20 +92,93 @@ func (r *VoterTokenRepo) MarkUsed(ctx context.Context, tx *sql.Tx, tokenID strin
 	return err
 }
 
+// LockLiveByVoter locks the voter's current non-revoked token, if any.
+func (r *VoterTokenRepo) LockLiveByVoter(ctx context.Context, tx *sql.Tx, voterID string) (*model.VoterToken, error) {
+	var vt model.VoterToken
+	err := scanVoterToken(tx.QueryRowContext(ctx,
+		`SELECT `+voterTokenColumns+` FROM voter_tokens WHERE voter_id = $1 AND status <> 'REVOKED' FOR UPDATE`,
+		voterID,
+	), &vt)
+	if err != nil {
+		return nil, err
+	}
+	return &vt, nil
+}
+
+// CreateInTx inserts a new ACTIVE token within a transaction.
+func (r *VoterTokenRepo) CreateInTx(ctx context.Context, tx *sql.Tx, eventID, voterID, token string) (*model.VoterToken, error) {
+	var vt model.VoterToken
+	err := scanVoterToken(tx.QueryRowContext(ctx,
+		`INSERT INTO voter_tokens (event_id, voter_id, token) VALUES ($1, $2, $3)
+		 RETURNING `+voterTokenColumns,
+		eventID, voterID, token,
+	), &vt)
+	if err != nil {
+		return nil, err
+	}
+	return &vt, nil
+}
+
+// Revoke marks an ACTIVE token as REVOKED within a transaction. The row is
+// kept so the token's history stays auditable.
+func (r *VoterTokenRepo) Revoke(ctx context.Context, tx *sql.Tx, tokenID, reason string) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE voter_tokens SET status = 'REVOKED', revoked_at = now(), revoke_reason = $2
+		 WHERE id = $1 AND status = 'ACTIVE'`,
+		tokenID, reason,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ResetDeliveries forgets the voter's delivery history so a replacement token
+// is picked up by an "unsent" delivery run.
+func (r *VoterTokenRepo) ResetDeliveries(ctx context.Context, tx *sql.Tx, voterID string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM token_deliveries WHERE voter_id = $1`, voterID)
+	return err
+}
+
+// ListByVoter returns every token ever issued to a voter, newest first.
+func (r *VoterTokenRepo) ListByVoter(ctx context.Context, voterID string) ([]model.VoterToken, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+voterTokenColumns+` FROM voter_tokens WHERE voter_id = $1 ORDER BY issued_at DESC`,
+		voterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []model.VoterToken
+	for rows.Next() {
+		var vt model.VoterToken
+		if err := scanVoterToken(rows, &vt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, vt)
+	}
+	return tokens, rows.Err()
+}
+
 // GetTokensForExport returns tokens with voter info for CSV export.
 type TokenExportRow struct {
-	FullName  string
-	NIMRaw    string
-	ClassName *string
-	Token     string
+	TokenID    string
+	FullName   string
+	NIMRaw     string
+	ClassName  *string
+	Token      string
+	Status     model.TokenStatus
+	VotingLink string // filled by the service when links are requested
 }
 
 func (r *VoterTokenRepo) GetTokensForExport(ctx context.Context, eventID string) ([]TokenExportRow, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT v.full_name, v.nim_raw, v.class_name, vt.token
+		`SELECT vt.id, v.full_name, v.nim_raw, v.class_name, vt.token, vt.status
 		 FROM voter_tokens vt
 		 JOIN voters v ON v.id = vt.voter_id
-		 WHERE vt.event_id = $1
+		 WHERE vt.event_id = $1 AND vt.status <> 'REVOKED'
 		 ORDER BY v.full_name`,
 		eventID,
 	)
@@ -89,7 +190,7 @@ func (r *VoterTokenRepo) GetTokensForExport(ctx context.Context, eventID string)
 	var result []TokenExportRow
 	for rows.Next() {
 		var row TokenExportRow
-		if err := rows.Scan(&row.FullName, &row.NIMRaw, &row.ClassName, &row.Token); err != nil {
+		if err := rows.Scan(&row.TokenID, &row.FullName, &row.NIMRaw, &row.ClassName, &row.Token, &row.Status); err != nil {
 			return nil, err
 		}
 		result = append(result, row)
@@ -118,3 +219,12 @@ func (r *VoterTokenRepo) GetTokenMapByEventID(ctx context.Context, eventID strin
 	}
 	return m, rows.Err()
 }
+
+// PurgeEvent deletes the event's voter tokens, for archival.
+func (r *VoterTokenRepo) PurgeEvent(ctx context.Context, tx *sql.Tx, eventID string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM voter_tokens WHERE event_id = $1`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+)
+
+var (
+	ErrEventNotLocked   = errors.New("only LOCKED events can be archived")
+	ErrEventArchived    = errors.New("event is already archived")
+	ErrEventNotDraft    = errors.New("only DRAFT or SCHEDULED events can be deleted")
+	ErrEventHasPayments = errors.New("event has a paid order and cannot be deleted")
+)
+
+// retentionCheckInterval is how often locked events are checked against the
+// retention period.
+const retentionCheckInterval = time.Hour
+
+// ArchiveService archives locked events, purging voter PII and tokens while
+// keeping ballots and the audit trail, and deletes events that never opened.
+// Every action is also recorded in the owner's account-level audit log.
+type ArchiveService struct {
+	db               *sql.DB
+	eventRepo        *repository.EventRepo
+	orderRepo        *repository.OrderRepo
+	voterRepo        *repository.VoterRepo
+	voterTokenRepo   *repository.VoterTokenRepo
+	deliveryRepo     *repository.DeliveryRepo
+	lockoutRepo      *repository.LockoutRepo
+	registrationRepo *repository.RegistrationRepo
+	importJobRepo    *repository.ImportJobRepo
+	auditLogRepo     *repository.AuditLogRepo
+	// retention is how long after locking an event is archived automatically.
+	retention time.Duration
+}
+
+func NewArchiveService(db *sql.DB, eventRepo *repository.EventRepo, orderRepo *repository.OrderRepo, voterRepo *repository.VoterRepo,
+	voterTokenRepo *repository.VoterTokenRepo, deliveryRepo *repository.DeliveryRepo, lockoutRepo *repository.LockoutRepo,
+	registrationRepo *repository.RegistrationRepo, importJobRepo *repository.ImportJobRepo, auditLogRepo *repository.AuditLogRepo,
+	retention time.Duration) *ArchiveService {
+	return &ArchiveService{
+		db:               db,
+		eventRepo:        eventRepo,
+		orderRepo:        orderRepo,
+		voterRepo:        voterRepo,
+		voterTokenRepo:   voterTokenRepo,
+		deliveryRepo:     deliveryRepo,
+		lockoutRepo:      lockoutRepo,
+		registrationRepo: registrationRepo,
+		importJobRepo:    importJobRepo,
+		auditLogRepo:     auditLogRepo,
+		retention:        retention,
+	}
+}
+
+func (s *ArchiveService) ownedEvent(ctx context.Context, eventID, userID string) (*model.Event, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+	return event, nil
+}
+
+// Archive purges a LOCKED event's voter PII and tokens now rather than at the
+// end of the retention period.
+func (s *ArchiveService) Archive(ctx context.Context, eventID, userID string) error {
+	if _, err := s.ownedEvent(ctx, eventID, userID); err != nil {
+		return err
+	}
+	return s.archive(ctx, eventID, &userID, "manual")
+}
+
+// archive pseudonymizes the event's voters and deletes its tokens,
+// deliveries, lockouts, registrations and import files in one transaction.
+// Ballots, slates, per-class and per-attribute turnout and the audit log are
+// kept; voter details are scrubbed from the audit metadata. The event row
+// lock makes concurrent archivers of the same event safe.
+func (s *ArchiveService) archive(ctx context.Context, eventID string, actor *string, trigger string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	event, err := s.eventRepo.LockForUpdate(ctx, tx, eventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+	if event.Status != model.EventStatusLocked {
+		return ErrEventNotLocked
+	}
+	if event.ArchivedAt != nil {
+		return ErrEventArchived
+	}
+
+	counts := map[string]interface{}{"trigger": trigger}
+	for _, step := range []struct {
+		name  string
+		purge func(context.Context, *sql.Tx, string) (int64, error)
+	}{
+		{"voters_pseudonymized", s.voterRepo.Pseudonymize},
+		{"tokens_deleted", s.voterTokenRepo.PurgeEvent},
+		{"deliveries_deleted", s.deliveryRepo.PurgeEvent},
+		{"lockouts_deleted", s.lockoutRepo.PurgeEvent},
+		{"registrations_deleted", s.registrationRepo.PurgeEvent},
+		{"import_jobs_deleted", s.importJobRepo.PurgeEvent},
+	} {
+		n, err := step.purge(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		counts[step.name] = n
+	}
+	if err := s.auditLogRepo.ScrubVoterPII(ctx, tx, eventID); err != nil {
+		return err
+	}
+	if err := s.eventRepo.MarkArchived(ctx, tx, eventID); err != nil {
+		return err
+	}
+
+	meta, _ := json.Marshal(counts)
+	if err := s.auditLogRepo.CreateInTx(ctx, tx, eventID, actor, "event.archived", string(meta)); err != nil {
+		return err
+	}
+	counts["title"] = event.Title
+	accountMeta, _ := json.Marshal(counts)
+	if err := s.auditLogRepo.CreateAccountInTx(ctx, tx, event.OwnerUserID, actor, &eventID, "event.archived", string(accountMeta)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes an event that has not opened yet, DRAFT or SCHEDULED, with
+// everything under it. Events with a paid order are kept so the payment stays
+// on record.
+func (s *ArchiveService) Delete(ctx context.Context, eventID, userID string) error {
+	event, err := s.ownedEvent(ctx, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if event.Status != model.EventStatusDraft && event.Status != model.EventStatusScheduled {
+		return ErrEventNotDraft
+	}
+	paid, err := s.orderRepo.HasPaid(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if paid {
+		return ErrEventHasPayments
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleted, err := s.eventRepo.DeleteDraft(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrEventNotDraft
+	}
+	meta, _ := json.Marshal(map[string]string{"title": event.Title})
+	if err := s.auditLogRepo.CreateAccountInTx(ctx, tx, userID, &userID, &eventID, "event.deleted", string(meta)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// StartRetention archives events locked longer than the retention period,
+// checking at once and then every retentionCheckInterval until ctx is done.
+func (s *ArchiveService) StartRetention(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(retentionCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.EnforceRetention(ctx); err != nil {
+				log.Printf("retention: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// EnforceRetention archives every event whose retention period has passed.
+// An event archived by another instance in the meantime is skipped.
+func (s *ArchiveService) EnforceRetention(ctx context.Context) error {
+	ids, err := s.eventRepo.ListArchivable(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.archive(ctx, id, nil, "retention"); err != nil && err != ErrEventArchived {
+			log.Printf("retention: failed to archive event %s: %v", id, err)
+		}
+	}
+	return nil
+}
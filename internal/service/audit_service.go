@@ -16,6 +16,21 @@ func NewAuditService(auditLogRepo *repository.AuditLogRepo, eventRepo *repositor
 	return &AuditService{auditLogRepo: auditLogRepo, eventRepo: eventRepo}
 }
 
+// ListAccount returns the user's account-level audit trail.
+func (s *AuditService) ListAccount(ctx context.Context, userID string, page, perPage int) (*dto.AccountAuditLogListResponse, error) {
+	logs, total, err := s.auditLogRepo.ListAccount(ctx, userID, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.AccountAuditLogListResponse{
+		Logs:    repository.AccountAuditLogsToDTO(logs),
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	}, nil
+}
+
 func (s *AuditService) List(ctx context.Context, eventID, userID string, page, perPage int) (*dto.AuditLogListResponse, error) {
 	event, err := s.eventRepo.GetByID(ctx, eventID)
 	if err != nil {
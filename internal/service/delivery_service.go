@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/config"
+	"github.com/amard/pemilo-golang/internal/delivery"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"golang.org/x/time/rate"
+)
+
+var (
+	ErrChannelNotConfigured = errors.New("delivery channel is not configured")
+)
+
+const deliverySendTimeout = 30 * time.Second
+
+// deliveryResumeInterval is how often queued deliveries without a dispatcher
+// are looked for, e.g. after the instance dispatching them stopped.
+const deliveryResumeInterval = time.Minute
+
+type deliveryRoute struct {
+	delivery.Route
+	limiter *rate.Limiter
+}
+
+type DeliveryService struct {
+	deliveryRepo *repository.DeliveryRepo
+	eventRepo    *repository.EventRepo
+	auditLogRepo *repository.AuditLogRepo
+	cfg          *config.Config
+	routes       map[model.DeliveryChannel]*deliveryRoute
+
+	mu      sync.Mutex
+	running map[string]bool // eventID|channel -> dispatcher active
+}
+
+func NewDeliveryService(
+	deliveryRepo *repository.DeliveryRepo,
+	eventRepo *repository.EventRepo,
+	auditLogRepo *repository.AuditLogRepo,
+	cfg *config.Config,
+	routes ...delivery.Route,
+) *DeliveryService {
+	m := make(map[model.DeliveryChannel]*deliveryRoute, len(routes))
+	for _, rt := range routes {
+		perSecond := rate.Limit(float64(rt.RatePerMinute) / 60.0)
+		m[rt.Provider.Channel()] = &deliveryRoute{Route: rt, limiter: rate.NewLimiter(perSecond, 1)}
+	}
+	return &DeliveryService{
+		deliveryRepo: deliveryRepo,
+		eventRepo:    eventRepo,
+		auditLogRepo: auditLogRepo,
+		cfg:          cfg,
+		routes:       m,
+		running:      make(map[string]bool),
+	}
+}
+
+// SendTokens queues token deliveries on the channel and starts dispatching them
+// in the background. By default only voters who have not received their token
+// yet are queued; with resend every voter with a contact is queued again.
+func (s *DeliveryService) SendTokens(ctx context.Context, eventID, userID string, channel model.DeliveryChannel, resend bool) (int64, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return 0, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return 0, ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return 0, ErrEventLocked
+	}
+	if _, ok := s.routes[channel]; !ok {
+		return 0, ErrChannelNotConfigured
+	}
+
+	queued, err := s.deliveryRepo.EnqueueForEvent(ctx, eventID, channel, resend)
+	if err != nil {
+		return 0, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"channel": channel, "resend": resend, "queued": queued})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "tokens.delivery_queued", string(meta))
+
+	s.dispatchAsync(eventID, channel)
+	return queued, nil
+}
+
+// Retry re-queues FAILED deliveries. BOUNCED ones stay as they are until the
+// voter's contact is corrected and SendTokens is called again.
+func (s *DeliveryService) Retry(ctx context.Context, eventID, userID string, channel model.DeliveryChannel) (int64, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return 0, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return 0, ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return 0, ErrEventLocked
+	}
+	if _, ok := s.routes[channel]; !ok {
+		return 0, ErrChannelNotConfigured
+	}
+
+	requeued, err := s.deliveryRepo.RequeueFailed(ctx, eventID, channel)
+	if err != nil {
+		return 0, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"channel": channel, "requeued": requeued})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "tokens.delivery_retried", string(meta))
+
+	s.dispatchAsync(eventID, channel)
+	return requeued, nil
+}
+
+func (s *DeliveryService) List(ctx context.Context, eventID, userID, status string) ([]model.TokenDelivery, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+
+	deliveries, err := s.deliveryRepo.ListByEvent(ctx, eventID, status)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []model.TokenDelivery{}
+	}
+	return deliveries, nil
+}
+
+// ResumeQueued starts dispatchers for deliveries left QUEUED by a previous
+// process, then repeats that every deliveryResumeInterval until ctx is done.
+// Events another instance is already dispatching are skipped by dispatch.
+// Called once at startup.
+func (s *DeliveryService) ResumeQueued(ctx context.Context) error {
+	if err := s.resume(ctx); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(deliveryResumeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := s.resume(ctx); err != nil {
+				log.Printf("[delivery] resume: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *DeliveryService) resume(ctx context.Context) error {
+	pending, err := s.deliveryRepo.ListQueuedEvents(ctx)
+	if err != nil {
+		return err
+	}
+	for eventID, channels := range pending {
+		for _, channel := range channels {
+			if _, ok := s.routes[channel]; ok {
+				s.dispatchAsync(eventID, channel)
+			}
+		}
+	}
+	return nil
+}
+
+// dispatchAsync starts a background dispatcher for eventID/channel unless one
+// is already running in this process; a running dispatcher picks up newly
+// queued rows itself.
+func (s *DeliveryService) dispatchAsync(eventID string, channel model.DeliveryChannel) {
+	key := eventID + "|" + string(channel)
+
+	s.mu.Lock()
+	if s.running[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[key] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, key)
+			s.mu.Unlock()
+		}()
+		if err := s.dispatch(context.Background(), eventID, channel); err != nil {
+			log.Printf("[delivery] event %s channel %s: %v", eventID, channel, err)
+		}
+	}()
+}
+
+// dispatch sends the queued deliveries of eventID/channel until none are left.
+// It holds the event's dispatch lock throughout, so with several instances
+// only one sends each delivery; the others return at once and the periodic
+// resume picks up anything queued after the holder finished.
+func (s *DeliveryService) dispatch(ctx context.Context, eventID string, channel model.DeliveryChannel) error {
+	route := s.routes[channel]
+
+	release, ok, err := s.deliveryRepo.LockDispatch(ctx, eventID, channel)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	defer release()
+
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deliveryRepo.DropOrphaned(ctx, eventID, channel); err != nil {
+		return err
+	}
+
+	for {
+		batch, err := s.deliveryRepo.ListQueued(ctx, eventID, channel, s.cfg.DeliveryBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, d := range batch {
+			if err := route.limiter.Wait(ctx); err != nil {
+				return err
+			}
+
+			err := s.send(ctx, route, event, d)
+			switch {
+			case err == nil:
+				err = s.deliveryRepo.MarkSent(ctx, d.ID)
+			case errors.Is(err, delivery.ErrBounced):
+				err = s.deliveryRepo.MarkFailed(ctx, d.ID, model.DeliveryStatusBounced, err.Error())
+			default:
+				err = s.deliveryRepo.MarkFailed(ctx, d.ID, model.DeliveryStatusFailed, err.Error())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *DeliveryService) send(ctx context.Context, route *deliveryRoute, event *model.Event, d repository.PendingDelivery) error {
+	msg, err := route.Template.Render(d.Recipient, delivery.TemplateData{
+		FullName:   d.FullName,
+		NIM:        d.NIMRaw,
+		Token:      d.Token,
+		EventTitle: event.Title,
+		VotingURL:  s.votingURL(event.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, deliverySendTimeout)
+	defer cancel()
+	return route.Provider.Send(sendCtx, msg)
+}
+
+func (s *DeliveryService) votingURL(eventID string) string {
+	return s.cfg.VoteBaseURL + "/" + eventID
+}
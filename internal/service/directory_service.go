@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/util"
+)
+
+var (
+	ErrDirectoryNotFound  = errors.New("directory not found")
+	ErrDirectoryForbidden = errors.New("you do not own this directory")
+	ErrDirectoryNameEmpty = errors.New("name is required")
+	ErrDirectoryNoDOB     = errors.New("directories do not keep dates of birth; import the roster with its dob column for an event with a DOB second factor")
+)
+
+// directoryNIMPolicy normalizes NIMs in a directory. It only removes
+// whitespace; each event applies its own policy when it pulls members.
+var directoryNIMPolicy = util.NIMPolicy{}
+
+type DirectoryService struct {
+	db            *sql.DB
+	directoryRepo *repository.DirectoryRepo
+	voterService  *VoterService
+}
+
+func NewDirectoryService(db *sql.DB, directoryRepo *repository.DirectoryRepo, voterService *VoterService) *DirectoryService {
+	return &DirectoryService{db: db, directoryRepo: directoryRepo, voterService: voterService}
+}
+
+func (s *DirectoryService) Create(ctx context.Context, userID string, req dto.DirectoryRequest) (*model.MemberDirectory, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrDirectoryNameEmpty
+	}
+	return s.directoryRepo.Create(ctx, userID, name)
+}
+
+func (s *DirectoryService) List(ctx context.Context, userID string) ([]model.MemberDirectory, error) {
+	directories, err := s.directoryRepo.ListByOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if directories == nil {
+		directories = []model.MemberDirectory{}
+	}
+	return directories, nil
+}
+
+func (s *DirectoryService) Get(ctx context.Context, directoryID, userID string) (*model.MemberDirectory, error) {
+	return s.ownedDirectory(ctx, directoryID, userID)
+}
+
+func (s *DirectoryService) Rename(ctx context.Context, directoryID, userID string, req dto.DirectoryRequest) (*model.MemberDirectory, error) {
+	if _, err := s.ownedDirectory(ctx, directoryID, userID); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrDirectoryNameEmpty
+	}
+	return s.directoryRepo.Rename(ctx, directoryID, name)
+}
+
+// Delete removes the directory. Voters pulled from it stay on their events.
+func (s *DirectoryService) Delete(ctx context.Context, directoryID, userID string) error {
+	if _, err := s.ownedDirectory(ctx, directoryID, userID); err != nil {
+		return err
+	}
+	return s.directoryRepo.Delete(ctx, directoryID)
+}
+
+func (s *DirectoryService) ownedDirectory(ctx context.Context, directoryID, userID string) (*model.MemberDirectory, error) {
+	directory, err := s.directoryRepo.GetByID(ctx, directoryID)
+	if err != nil {
+		return nil, ErrDirectoryNotFound
+	}
+	if directory.OwnerUserID != userID {
+		return nil, ErrDirectoryForbidden
+	}
+	return directory, nil
+}
+
+// ListMembers returns one page of the directory's members ordered by name,
+// and the number of matches.
+func (s *DirectoryService) ListMembers(ctx context.Context, directoryID, userID string, filter repository.DirectoryMemberFilter, page, perPage int) ([]model.DirectoryMember, int, error) {
+	if _, err := s.ownedDirectory(ctx, directoryID, userID); err != nil {
+		return nil, 0, err
+	}
+	if perPage <= 0 {
+		perPage = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+	members, total, err := s.directoryRepo.ListMembers(ctx, directoryID, filter, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	if members == nil {
+		members = []model.DirectoryMember{}
+	}
+	return members, total, nil
+}
+
+// Import loads a roster file into the directory, keyed on the NIM. Without
+// sync, NIMs already in the directory are rejected as duplicates. With sync,
+// their details are replaced by the file's, and with deactivateMissing active
+// members absent from the file are deactivated. Events that already pulled
+// those members are not affected. Dates of birth in the file are not stored.
+func (s *DirectoryService) Import(ctx context.Context, directoryID, userID string, upload RosterUpload, sync, deactivateMissing bool) (*dto.DirectoryImportResult, error) {
+	if _, err := s.ownedDirectory(ctx, directoryID, userID); err != nil {
+		return nil, err
+	}
+
+	parsed, err := upload.parse(directoryNIMPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.DirectoryImportResult{Rejected: []dto.ImportReject{}}
+	var fileNIMs []string
+	for _, r := range parsed.Rejected {
+		result.Rejected = append(result.Rejected, dto.ImportReject{Row: r.Row, Reason: r.Reason})
+		if r.NIM != "" {
+			fileNIMs = append(fileNIMs, r.NIM)
+		}
+	}
+	rows := make([]repository.DirectoryMemberRow, 0, len(parsed.Rows))
+	rowOf := make(map[string]int, len(parsed.Rows))
+	for _, row := range parsed.Rows {
+		fileNIMs = append(fileNIMs, row.NIMNormalized)
+		if tooLongAttribute(row.Attributes) {
+			result.Rejected = append(result.Rejected, dto.ImportReject{Row: row.Row, Reason: ErrAttributeTooLong.Error()})
+			continue
+		}
+		rowOf[row.NIMNormalized] = row.Row
+		rows = append(rows, repository.DirectoryMemberRow{
+			FullName:      row.FullName,
+			NIMRaw:        row.NIMRaw,
+			NIMNormalized: row.NIMNormalized,
+			ClassName:     row.ClassName,
+			Email:         row.Email,
+			Phone:         row.Phone,
+			Attributes:    row.Attributes,
+		})
+	}
+	if deactivateMissing && len(fileNIMs) == 0 {
+		return nil, ErrEmptyRoster
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inserted, updated, err := s.directoryRepo.UpsertMembers(ctx, tx, directoryID, rows, sync)
+	if err != nil {
+		return nil, err
+	}
+	result.AddedCount, result.UpdatedCount = len(inserted), len(updated)
+	for _, row := range rows {
+		if inserted[row.NIMNormalized] || updated[row.NIMNormalized] {
+			continue
+		}
+		if sync {
+			result.UnchangedCount++
+		} else {
+			result.Rejected = append(result.Rejected, dto.ImportReject{Row: rowOf[row.NIMNormalized], Reason: "duplicate nim in directory"})
+		}
+	}
+
+	if sync && deactivateMissing {
+		deactivated, err := s.directoryRepo.DeactivateMissing(ctx, tx, directoryID, fileNIMs)
+		if err != nil {
+			return nil, err
+		}
+		result.DeactivatedCount = int(deactivated)
+	}
+	if err := s.directoryRepo.Touch(ctx, tx, directoryID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	sortRejects(result.Rejected)
+	return result, nil
+}
+
+func tooLongAttribute(attrs map[string]string) bool {
+	for _, v := range attrs {
+		if len(v) > util.MaxAttributeValueLen {
+			return true
+		}
+	}
+	return false
+}
+
+// PullIntoEvent copies the directory's active members matching the request's
+// filter onto the event's roster. The voters are snapshots: later directory
+// changes do not reach them. Members whose NIM is already on the roster are
+// skipped. Events with a DOB second factor are refused, since directories hold
+// no dates of birth.
+func (s *DirectoryService) PullIntoEvent(ctx context.Context, eventID, userID string, req dto.PullDirectoryRequest) (*dto.DirectoryPullResult, error) {
+	event, err := s.voterService.importableEvent(ctx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ownedDirectory(ctx, req.DirectoryID, userID); err != nil {
+		return nil, err
+	}
+	if event.SecondFactor != nil && *event.SecondFactor == model.SecondFactorDOB {
+		return nil, ErrDirectoryNoDOB
+	}
+
+	active := true
+	members, err := s.directoryRepo.ListMatching(ctx, req.DirectoryID, repository.DirectoryMemberFilter{
+		ClassNames: req.ClassNames,
+		Attributes: req.Attributes,
+		Active:     &active,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.voterService.addMembers(ctx, event, members)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"directory_id":    req.DirectoryID,
+		"class_names":     req.ClassNames,
+		"attributes":      req.Attributes,
+		"added":           result.AddedCount,
+		"already_present": result.AlreadyPresentCount,
+		"rejected":        len(result.Rejected),
+	})
+	s.voterService.auditLogRepo.Create(ctx, eventID, &userID, "voters.pulled_from_directory", string(meta))
+
+	return result, nil
+}
+
+// addMembers adds directory members to the event as voters, normalizing
+// their NIMs with the event's policy, in one transaction within the package
+// limit.
+func (s *VoterService) addMembers(ctx context.Context, event *model.Event, members []model.DirectoryMember) (*dto.DirectoryPullResult, error) {
+	result := &dto.DirectoryPullResult{Rejected: []dto.DirectoryPullReject{}}
+	reject := func(m *model.DirectoryMember, reason string) {
+		result.Rejected = append(result.Rejected, dto.DirectoryPullReject{MemberID: m.ID, NIMRaw: m.NIMRaw, FullName: m.FullName, Reason: reason})
+	}
+
+	policy := nimPolicy(event)
+	var rows []repository.VoterInsertRow
+	seen := make(map[string]bool, len(members))
+	nims := make([]string, 0, len(members))
+	for i := range members {
+		m := &members[i]
+		row, _, err := voterInputRow(policy, m.FullName, m.NIMRaw, deref(m.ClassName), deref(m.Email), deref(m.Phone), "")
+		if err != nil {
+			reject(m, err.Error())
+			continue
+		}
+		if seen[row.NIMNormalized] {
+			reject(m, "duplicate nim under the event's nim format")
+			continue
+		}
+		seen[row.NIMNormalized] = true
+		row.Row = i
+		if row.Attributes, err = eventAttributes(event, m.Attributes); err != nil {
+			reject(m, err.Error())
+			continue
+		}
+		if err := applySecondFactor(s.cfg.SecondFactorKey, event, &row, ""); err != nil {
+			reject(m, err.Error())
+			continue
+		}
+		rows = append(rows, row)
+		nims = append(nims, row.NIMNormalized)
+	}
+
+	existing, err := s.voterRepo.ListByNIMs(ctx, event.ID, nims)
+	if err != nil {
+		return nil, err
+	}
+	onRoster := make(map[string]bool, len(existing))
+	for _, v := range existing {
+		onRoster[v.NIMNormalized] = true
+	}
+	newRows := rows[:0]
+	for _, row := range rows {
+		if onRoster[row.NIMNormalized] {
+			result.AlreadyPresentCount++
+			continue
+		}
+		newRows = append(newRows, row)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkRosterLimit(ctx, tx, event.ID, len(newRows)); err != nil {
+		return nil, err
+	}
+	added, dbRejected, err := s.bulkInsert(ctx, tx, event.ID, newRows, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	result.AddedCount = added
+	// Added to the roster since it was read.
+	result.AlreadyPresentCount += len(dbRejected)
+	return result, nil
+}
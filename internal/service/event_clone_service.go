@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/amard/pemilo-golang/internal/config"
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/util"
+)
+
+var (
+	ErrTemplateNotFound  = errors.New("template not found")
+	ErrTemplateNameTaken = errors.New("you already have a template with this name")
+	ErrTemplateNameEmpty = errors.New("name is required")
+	ErrCloneArchived     = errors.New("an archived event can only be cloned without its voters and slates")
+)
+
+// CloneResult is the new event and what happened to the source's voters.
+// Clones start on the FREE package, so a roster over its voter limit is left
+// out: VotersSkipped is then non-zero and the roster can be imported once the
+// clone's package is upgraded.
+type CloneResult struct {
+	Event         *model.Event `json:"event"`
+	VotersCopied  int          `json:"voters_copied"`
+	VotersSkipped int          `json:"voters_skipped"`
+}
+
+// EventCloneService starts new DRAFT events from an existing event or from a
+// saved template. New events always start on the FREE package.
+type EventCloneService struct {
+	db           *sql.DB
+	eventRepo    *repository.EventRepo
+	slateRepo    *repository.SlateRepo
+	voterRepo    *repository.VoterRepo
+	templateRepo *repository.TemplateRepo
+	auditLogRepo *repository.AuditLogRepo
+	cfg          *config.Config
+}
+
+func NewEventCloneService(db *sql.DB, eventRepo *repository.EventRepo, slateRepo *repository.SlateRepo, voterRepo *repository.VoterRepo, templateRepo *repository.TemplateRepo, auditLogRepo *repository.AuditLogRepo, cfg *config.Config) *EventCloneService {
+	return &EventCloneService{
+		db:           db,
+		eventRepo:    eventRepo,
+		slateRepo:    slateRepo,
+		voterRepo:    voterRepo,
+		templateRepo: templateRepo,
+		auditLogRepo: auditLogRepo,
+		cfg:          cfg,
+	}
+}
+
+func eventSettings(e *model.Event) model.EventSettings {
+	return model.EventSettings{
+		TokenLength:     e.TokenLength,
+		TokenAlphabet:   e.TokenAlphabet,
+		TokenCheckChar:  e.TokenCheckChar,
+		NIMStripChars:   e.NIMStripChars,
+		NIMCaseFold:     e.NIMCaseFold,
+		NIMTrimZeros:    e.NIMTrimZeros,
+		NIMPattern:      e.NIMPattern,
+		SecondFactor:    e.SecondFactor,
+		ProxyCap:        e.ProxyCap,
+		VoterAttributes: e.VoterAttributes,
+	}
+}
+
+func applyEventSettings(e *model.Event, s model.EventSettings) {
+	e.TokenLength, e.TokenAlphabet, e.TokenCheckChar = s.TokenLength, s.TokenAlphabet, s.TokenCheckChar
+	e.NIMStripChars, e.NIMCaseFold, e.NIMTrimZeros, e.NIMPattern = s.NIMStripChars, s.NIMCaseFold, s.NIMTrimZeros, s.NIMPattern
+	e.SecondFactor = s.SecondFactor
+	e.ProxyCap = s.ProxyCap
+	e.VoterAttributes = s.VoterAttributes
+}
+
+// newDraft is a FREE package event with default settings.
+func newDraft(title string, description *string) *model.Event {
+	limits := model.PackageLimitsMap[model.PackageFree]
+	return &model.Event{
+		Title:          title,
+		Description:    description,
+		MaxSlates:      limits.MaxSlates,
+		MaxVoters:      limits.MaxVoters,
+		Package:        model.PackageFree,
+		TokenLength:    util.DefaultTokenPolicy.Length,
+		TokenAlphabet:  util.DefaultTokenPolicy.Alphabet,
+		TokenCheckChar: util.DefaultTokenPolicy.CheckChar,
+	}
+}
+
+func (s *EventCloneService) ownedEvent(ctx context.Context, eventID, userID string) (*model.Event, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+	return event, nil
+}
+
+// slatesWithMembers loads the event's slates with their members, without ids.
+func (s *EventCloneService) slatesWithMembers(ctx context.Context, eventID string) ([]model.Slate, error) {
+	slates, err := s.slateRepo.ListByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range slates {
+		members, err := s.slateRepo.ListMembersBySlate(ctx, slates[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		for j := range members {
+			members[j].ID, members[j].SlateID = "", ""
+		}
+		slates[i].ID, slates[i].EventID = "", ""
+		slates[i].Members = members
+	}
+	return slates, nil
+}
+
+// Clone copies an event into a new DRAFT owned by the same user. The title
+// and description are always copied; slates with their members, eligible
+// voters and settings only on request. Copied voters bring the source's NIM
+// policy and attribute keys along, since their stored NIMs and attributes
+// depend on them. Second-factor hashes are keyed per event: phone hashes are
+// recomputed for the clone, but DOB hashes cannot be, so copying voters along
+// with a DOB second factor is refused with SecondFactorMissingError, as is a
+// phone factor some copied voters have no phone for. A roster larger than the FREE voter limit
+// is not copied; the clone is created without it and the response says how
+// many voters were skipped. An archived event's roster is pseudonymized, so
+// only its title, description and settings can be cloned.
+func (s *EventCloneService) Clone(ctx context.Context, eventID, userID string, req dto.CloneEventRequest) (*CloneResult, error) {
+	src, err := s.ownedEvent(ctx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if src.ArchivedAt != nil && (req.CopyVoters || req.CopySlates) {
+		return nil, ErrCloneArchived
+	}
+
+	title := src.Title + " (copy)"
+	if req.Title != nil && strings.TrimSpace(*req.Title) != "" {
+		title = strings.TrimSpace(*req.Title)
+	}
+	draft := newDraft(title, src.Description)
+	if req.CopySettings {
+		applyEventSettings(draft, eventSettings(src))
+	} else if req.CopyVoters {
+		draft.NIMStripChars, draft.NIMCaseFold, draft.NIMTrimZeros, draft.NIMPattern = src.NIMStripChars, src.NIMCaseFold, src.NIMTrimZeros, src.NIMPattern
+		draft.VoterAttributes = src.VoterAttributes
+	}
+
+	var slates []model.Slate
+	if req.CopySlates {
+		if slates, err = s.slatesWithMembers(ctx, src.ID); err != nil {
+			return nil, err
+		}
+		if len(slates) > draft.MaxSlates {
+			return nil, ErrMaxSlatesReached
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	event, err := s.eventRepo.CreateInTx(ctx, tx, userID, draft)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.slateRepo.CopyInTx(ctx, tx, event.ID, slates); err != nil {
+		return nil, err
+	}
+	resp := &CloneResult{Event: event}
+	if req.CopyVoters {
+		// Roster changes take the event row lock, so the count below still
+		// holds when the voters are copied.
+		if _, err := s.eventRepo.LockForUpdate(ctx, tx, src.ID); err != nil {
+			return nil, err
+		}
+		eligible, err := s.voterRepo.CountEligibleInTx(ctx, tx, src.ID)
+		if err != nil {
+			return nil, err
+		}
+		if eligible > event.MaxVoters {
+			resp.VotersSkipped = eligible
+		} else {
+			copied, err := s.voterRepo.CopyEligible(ctx, tx, src.ID, event.ID)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := hashMissingPhones(ctx, tx, s.voterRepo, s.cfg.SecondFactorKey, event.ID); err != nil {
+				return nil, err
+			}
+			if err := checkSecondFactorCoverage(ctx, tx, s.voterRepo, event.ID, draft.SecondFactor); err != nil {
+				return nil, err
+			}
+			resp.VotersCopied = int(copied)
+		}
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"cloned_from":    src.ID,
+		"slates":         len(slates),
+		"voters":         resp.VotersCopied,
+		"voters_skipped": resp.VotersSkipped,
+		"settings":       req.CopySettings,
+	})
+	if err := s.auditLogRepo.CreateInTx(ctx, tx, event.ID, &userID, "event.created", string(meta)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SaveTemplate stores the event's title, description and settings, and with
+// includeSlates its slates and members, as a named template.
+func (s *EventCloneService) SaveTemplate(ctx context.Context, eventID, userID string, req dto.CreateTemplateRequest) (*model.EventTemplate, error) {
+	src, err := s.ownedEvent(ctx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrTemplateNameEmpty
+	}
+
+	t := &model.EventTemplate{
+		OwnerUserID:   userID,
+		Name:          name,
+		SourceEventID: &src.ID,
+		Title:         src.Title,
+		Description:   src.Description,
+		Settings:      eventSettings(src),
+	}
+	if req.IncludeSlates {
+		if t.Slates, err = s.slatesWithMembers(ctx, src.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	created, err := s.templateRepo.Create(ctx, t)
+	if err != nil {
+		if strings.Contains(err.Error(), "uq_event_templates_owner_name") {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"template_id": created.ID, "name": created.Name})
+	s.auditLogRepo.Create(ctx, src.ID, &userID, "event.template_saved", string(meta))
+	return created, nil
+}
+
+func (s *EventCloneService) ListTemplates(ctx context.Context, userID string) ([]model.EventTemplate, error) {
+	templates, err := s.templateRepo.ListByOwner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if templates == nil {
+		templates = []model.EventTemplate{}
+	}
+	return templates, nil
+}
+
+func (s *EventCloneService) GetTemplate(ctx context.Context, templateID, userID string) (*model.EventTemplate, error) {
+	t, err := s.templateRepo.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	if t.OwnerUserID != userID {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+func (s *EventCloneService) DeleteTemplate(ctx context.Context, templateID, userID string) error {
+	if _, err := s.GetTemplate(ctx, templateID, userID); err != nil {
+		return err
+	}
+	return s.templateRepo.Delete(ctx, templateID)
+}
+
+// CreateFromTemplate starts a new DRAFT event from a template. It has no
+// voters yet; those added later must satisfy the template's second factor,
+// which EventService.Open checks again before voting starts.
+func (s *EventCloneService) CreateFromTemplate(ctx context.Context, templateID, userID string, req dto.CreateFromTemplateRequest) (*model.Event, error) {
+	t, err := s.GetTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	title := t.Title
+	if req.Title != nil && strings.TrimSpace(*req.Title) != "" {
+		title = strings.TrimSpace(*req.Title)
+	}
+	draft := newDraft(title, t.Description)
+	applyEventSettings(draft, t.Settings)
+	if len(t.Slates) > draft.MaxSlates {
+		return nil, ErrMaxSlatesReached
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	event, err := s.eventRepo.CreateInTx(ctx, tx, userID, draft)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.slateRepo.CopyInTx(ctx, tx, event.ID, t.Slates); err != nil {
+		return nil, err
+	}
+	meta, _ := json.Marshal(map[string]interface{}{"template_id": t.ID, "slates": len(t.Slates)})
+	if err := s.auditLogRepo.CreateInTx(ctx, tx, event.ID, &userID, "event.created", string(meta)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -2,28 +2,123 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/amard/pemilo-golang/internal/dto"
 	"github.com/amard/pemilo-golang/internal/model"
 	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/util"
 )
 
 var (
-	ErrEventNotFound     = errors.New("event not found")
-	ErrEventForbidden    = errors.New("forbidden: not event owner")
-	ErrEventLocked       = errors.New("event is locked, no modifications allowed")
-	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrEventNotFound      = errors.New("event not found")
+	ErrEventForbidden     = errors.New("forbidden: not event owner")
+	ErrEventLocked        = errors.New("event is locked, no modifications allowed")
+	ErrInvalidTransition  = errors.New("invalid status transition")
+	ErrInvalidTokenPolicy = errors.New("invalid token policy: length 6-24, alphabet of 10+ unique A-Z/0-9 characters, at least 30 bits of entropy")
+	ErrTokenPolicyLocked  = errors.New("token policy cannot change after tokens have been issued")
+	ErrSecondFactorLocked = errors.New("second factor cannot change while voting is open")
+	ErrInvalidNIMPolicy   = errors.New("invalid nim policy: strip_chars up to 32 characters and a valid regular expression pattern")
+	ErrNIMPolicyLocked    = errors.New("nim policy cannot change once the event has voters")
+	ErrInvalidAttributes  = errors.New("invalid voter attributes: unique lower_snake_case keys of up to 32 characters, not a roster column")
+	ErrInvalidProxyCap    = errors.New("proxy_cap must be between 0 and 20")
 )
 
+// maxProxyCap is the most members one voter may represent.
+const maxProxyCap = 20
+
+// SecondFactorMissingError rejects a second factor some eligible voters have
+// no value on file for, since they could not vote.
+type SecondFactorMissingError struct {
+	Factor  model.SecondFactor
+	Missing int
+}
+
+func (e *SecondFactorMissingError) Error() string {
+	what := "date of birth"
+	if e.Factor == model.SecondFactorPhoneLast4 {
+		what = "phone number"
+	}
+	return fmt.Sprintf("%d eligible voters have no %s on file for the %s second factor", e.Missing, what, e.Factor)
+}
+
+// checkSecondFactorCoverage returns a SecondFactorMissingError when factor is
+// set and some of the event's eligible voters have no value on file for it.
+func checkSecondFactorCoverage(ctx context.Context, tx *sql.Tx, voterRepo *repository.VoterRepo, eventID string, factor *model.SecondFactor) error {
+	if factor == nil {
+		return nil
+	}
+	missing, err := voterRepo.CountMissingSecondFactor(ctx, tx, eventID, *factor)
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		return &SecondFactorMissingError{Factor: *factor, Missing: missing}
+	}
+	return nil
+}
+
 type EventService struct {
-	eventRepo    *repository.EventRepo
-	auditLogRepo *repository.AuditLogRepo
+	db             *sql.DB
+	eventRepo      *repository.EventRepo
+	voterRepo      *repository.VoterRepo
+	voterTokenRepo *repository.VoterTokenRepo
+	auditLogRepo   *repository.AuditLogRepo
+}
+
+func NewEventService(db *sql.DB, eventRepo *repository.EventRepo, voterRepo *repository.VoterRepo, voterTokenRepo *repository.VoterTokenRepo, auditLogRepo *repository.AuditLogRepo) *EventService {
+	return &EventService{db: db, eventRepo: eventRepo, voterRepo: voterRepo, voterTokenRepo: voterTokenRepo, auditLogRepo: auditLogRepo}
+}
+
+// tokenPolicy returns the token format configured for an event.
+func tokenPolicy(e *model.Event) util.TokenPolicy {
+	return util.TokenPolicy{Length: e.TokenLength, Alphabet: e.TokenAlphabet, CheckChar: e.TokenCheckChar}
+}
+
+// tokenPolicyFromRequest validates a requested policy, filling the default alphabet.
+func tokenPolicyFromRequest(req *dto.TokenPolicyRequest) (util.TokenPolicy, error) {
+	policy := util.TokenPolicy{Length: req.Length, Alphabet: strings.ToUpper(req.Alphabet), CheckChar: req.CheckChar}
+	if policy.Alphabet == "" {
+		policy.Alphabet = util.UnambiguousTokenAlphabet
+	}
+	if err := policy.Validate(); err != nil {
+		return policy, ErrInvalidTokenPolicy
+	}
+	return policy, nil
+}
+
+// nimPolicy returns the NIM normalization configured for an event.
+func nimPolicy(e *model.Event) util.NIMPolicy {
+	return util.NIMPolicy{StripChars: e.NIMStripChars, CaseFold: e.NIMCaseFold, TrimLeadingZeros: e.NIMTrimZeros, Pattern: e.NIMPattern}
 }
 
-func NewEventService(eventRepo *repository.EventRepo, auditLogRepo *repository.AuditLogRepo) *EventService {
-	return &EventService{eventRepo: eventRepo, auditLogRepo: auditLogRepo}
+func nimPolicyFromRequest(req *dto.NIMPolicyRequest) (util.NIMPolicy, error) {
+	policy := util.NIMPolicy{StripChars: req.StripChars, CaseFold: req.CaseFold, TrimLeadingZeros: req.TrimLeadingZeros, Pattern: strings.TrimSpace(req.Pattern)}
+	if err := policy.Validate(); err != nil {
+		return policy, ErrInvalidNIMPolicy
+	}
+	return policy, nil
+}
+
+// secondFactorFromRequest maps the request value to a factor; NONE clears it.
+func secondFactorFromRequest(v *string) *model.SecondFactor {
+	if v == nil || *v == "" || *v == "NONE" {
+		return nil
+	}
+	factor := model.SecondFactor(*v)
+	return &factor
+}
+
+func sameSecondFactor(a, b *model.SecondFactor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 func (s *EventService) Create(ctx context.Context, ownerID string, req dto.CreateEventRequest) (*model.Event, error) {
@@ -39,11 +134,41 @@ func (s *EventService) Create(ctx context.Context, ownerID string, req dto.Creat
 		closesAt = &v
 	}
 
-	event, err := s.eventRepo.Create(ctx, ownerID, req.Title, req.Description, opensAt, closesAt, limits.MaxSlates, limits.MaxVoters, string(model.PackageFree))
+	policy := util.DefaultTokenPolicy
+	if req.TokenPolicy != nil {
+		var err error
+		if policy, err = tokenPolicyFromRequest(req.TokenPolicy); err != nil {
+			return nil, err
+		}
+	}
+	var nim util.NIMPolicy
+	if req.NIMPolicy != nil {
+		var err error
+		if nim, err = nimPolicyFromRequest(req.NIMPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	event, err := s.eventRepo.Create(ctx, ownerID, req.Title, req.Description, opensAt, closesAt, limits.MaxSlates, limits.MaxVoters, string(model.PackageFree),
+		policy.Length, policy.Alphabet, policy.CheckChar)
 	if err != nil {
 		return nil, err
 	}
 
+	if nim != (util.NIMPolicy{}) {
+		if err := s.eventRepo.UpdateNIMPolicy(ctx, event.ID, nim.StripChars, nim.CaseFold, nim.TrimLeadingZeros, nim.Pattern); err != nil {
+			return nil, err
+		}
+		event.NIMStripChars, event.NIMCaseFold, event.NIMTrimZeros, event.NIMPattern = nim.StripChars, nim.CaseFold, nim.TrimLeadingZeros, nim.Pattern
+	}
+
+	if factor := secondFactorFromRequest(req.SecondFactor); factor != nil {
+		if err := s.eventRepo.UpdateSecondFactor(ctx, event.ID, factor); err != nil {
+			return nil, err
+		}
+		event.SecondFactor = factor
+	}
+
 	s.auditLogRepo.Create(ctx, event.ID, &ownerID, "event.created", `{}`)
 	return event, nil
 }
@@ -72,6 +197,10 @@ func (s *EventService) GetPublicInfo(ctx context.Context, eventID string) (*dto.
 		Status:      string(event.Status),
 		OpensAt:     event.OpensAt,
 		ClosesAt:    event.ClosesAt,
+		TokenLength: tokenPolicy(event).TokenLen(),
+
+		RegistrationOpen: acceptsRegistrations(event),
+		SecondFactor:     (*string)(event.SecondFactor),
 	}, nil
 }
 
@@ -79,14 +208,19 @@ func (s *EventService) List(ctx context.Context, userID string) ([]model.Event,
 	return s.eventRepo.ListByOwner(ctx, userID)
 }
 
+// eventChange is an audit entry for one setting changed by Update.
+type eventChange struct {
+	action string
+	meta   map[string]interface{}
+}
+
+// Update validates every requested change against the locked event before
+// saving any of them, then saves the details, settings and their audit
+// entries in one transaction.
 func (s *EventService) Update(ctx context.Context, eventID, userID string, req dto.UpdateEventRequest) (*model.Event, error) {
-	event, err := s.GetByID(ctx, eventID, userID)
-	if err != nil {
+	if _, err := s.GetByID(ctx, eventID, userID); err != nil {
 		return nil, err
 	}
-	if event.Status == model.EventStatusLocked {
-		return nil, ErrEventLocked
-	}
 
 	var opensAt, closesAt *string
 	if req.OpensAt != nil {
@@ -97,16 +231,129 @@ func (s *EventService) Update(ctx context.Context, eventID, userID string, req d
 		v := req.ClosesAt.Format(time.RFC3339)
 		closesAt = &v
 	}
+	if req.ProxyCap != nil && (*req.ProxyCap < 0 || *req.ProxyCap > maxProxyCap) {
+		return nil, ErrInvalidProxyCap
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The row lock keeps the status and roster checks below valid until commit
+	event, err := s.eventRepo.LockForUpdate(ctx, tx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.Status == model.EventStatusLocked {
+		return nil, ErrEventLocked
+	}
+
+	settings := eventSettings(event)
+	registrationOpen := event.RegistrationOpen
+	var changes []eventChange
+
+	if req.TokenPolicy != nil {
+		policy, err := tokenPolicyFromRequest(req.TokenPolicy)
+		if err != nil {
+			return nil, err
+		}
+		issued, err := s.voterTokenRepo.CountByEventInTx(ctx, tx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if issued > 0 && policy != tokenPolicy(event) {
+			return nil, ErrTokenPolicyLocked
+		}
+		settings.TokenLength, settings.TokenAlphabet, settings.TokenCheckChar = policy.Length, policy.Alphabet, policy.CheckChar
+		changes = append(changes, eventChange{"event.token_policy_changed", map[string]interface{}{
+			"length": policy.Length, "alphabet": policy.Alphabet, "check_char": policy.CheckChar,
+		}})
+	}
+
+	if req.NIMPolicy != nil {
+		policy, err := nimPolicyFromRequest(req.NIMPolicy)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nimPolicy(event) {
+			// Stored nim_normalized values were produced by the old policy.
+			voters, err := s.voterRepo.CountByEventInTx(ctx, tx, eventID)
+			if err != nil {
+				return nil, err
+			}
+			if voters > 0 {
+				return nil, ErrNIMPolicyLocked
+			}
+			settings.NIMStripChars, settings.NIMCaseFold, settings.NIMTrimZeros, settings.NIMPattern = policy.StripChars, policy.CaseFold, policy.TrimLeadingZeros, policy.Pattern
+			changes = append(changes, eventChange{"event.nim_policy_changed", map[string]interface{}{
+				"strip_chars": policy.StripChars, "case_fold": policy.CaseFold,
+				"trim_leading_zeros": policy.TrimLeadingZeros, "pattern": policy.Pattern,
+			}})
+		}
+	}
 
-	updated, err := s.eventRepo.Update(ctx, eventID, req.Title, req.Description, opensAt, closesAt)
+	if req.SecondFactor != nil {
+		factor := secondFactorFromRequest(req.SecondFactor)
+		if !sameSecondFactor(factor, event.SecondFactor) {
+			if event.Status == model.EventStatusOpen {
+				return nil, ErrSecondFactorLocked
+			}
+			if err := checkSecondFactorCoverage(ctx, tx, s.voterRepo, eventID, factor); err != nil {
+				return nil, err
+			}
+			settings.SecondFactor = factor
+			changes = append(changes, eventChange{"event.second_factor_changed", map[string]interface{}{"second_factor": factor}})
+		}
+	}
+
+	if req.ProxyCap != nil && *req.ProxyCap != event.ProxyCap {
+		settings.ProxyCap = *req.ProxyCap
+		changes = append(changes, eventChange{"event.proxy_cap_changed", map[string]interface{}{"from": event.ProxyCap, "to": *req.ProxyCap}})
+	}
+
+	if req.VoterAttributes != nil {
+		keys, err := voterAttributeKeys(*req.VoterAttributes)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Join(keys, ",") != strings.Join(event.VoterAttributes, ",") {
+			settings.VoterAttributes = keys
+			changes = append(changes, eventChange{"event.voter_attributes_changed", map[string]interface{}{"from": event.VoterAttributes, "to": keys}})
+		}
+	}
+
+	if req.RegistrationOpen != nil && *req.RegistrationOpen != event.RegistrationOpen {
+		registrationOpen = *req.RegistrationOpen
+		action := "event.registration_closed"
+		if registrationOpen {
+			action = "event.registration_opened"
+		}
+		changes = append(changes, eventChange{action, map[string]interface{}{}})
+	}
+
+	updated, err := s.eventRepo.UpdateInTx(ctx, tx, eventID, req.Title, req.Description, opensAt, closesAt, settings, registrationOpen)
 	if err != nil {
 		return nil, err
 	}
 
-	s.auditLogRepo.Create(ctx, eventID, &userID, "event.updated", `{}`)
+	changes = append(changes, eventChange{"event.updated", map[string]interface{}{}})
+	for _, c := range changes {
+		meta, _ := json.Marshal(c.meta)
+		if err := s.auditLogRepo.CreateInTx(ctx, tx, eventID, &userID, c.action, string(meta)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return updated, nil
 }
 
+// Open starts voting. It refuses while some eligible voters have no value on
+// file for the event's second factor, e.g. voters cloned or approved before
+// the factor was chosen.
 func (s *EventService) Open(ctx context.Context, eventID, userID string) error {
 	event, err := s.GetByID(ctx, eventID, userID)
 	if err != nil {
@@ -115,6 +362,16 @@ func (s *EventService) Open(ctx context.Context, eventID, userID string) error {
 	if event.Status != model.EventStatusDraft && event.Status != model.EventStatusScheduled && event.Status != model.EventStatusClosed {
 		return ErrInvalidTransition
 	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkSecondFactorCoverage(ctx, tx, s.voterRepo, eventID, event.SecondFactor); err != nil {
+		return err
+	}
 	if err := s.eventRepo.UpdateStatus(ctx, eventID, model.EventStatusOpen); err != nil {
 		return err
 	}
@@ -160,3 +417,18 @@ func (s *EventService) GetByIDPublic(ctx context.Context, eventID string) (*mode
 	}
 	return event, nil
 }
+
+// voterAttributeKeys normalizes requested attribute keys, keeping their order.
+func voterAttributeKeys(requested []string) ([]string, error) {
+	keys := make([]string, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for _, k := range requested {
+		key := util.NormalizeAttributeKey(k)
+		if !util.ValidAttributeKey(key) || seen[key] {
+			return nil, ErrInvalidAttributes
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
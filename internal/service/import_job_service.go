@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+)
+
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+)
+
+const (
+	// importLeaseTTL is how long a RUNNING job's lease lasts without a
+	// heartbeat before another instance may requeue it.
+	importLeaseTTL = time.Minute
+	// importHeartbeatInterval is how often a worker renews its job's lease.
+	importHeartbeatInterval = 10 * time.Second
+)
+
+// ImportJobService runs voter imports in the background. Job state lives in
+// Postgres, so queued jobs survive a restart, and the jobs of one event run
+// one at a time. A running job is leased to the process running it; only a
+// job whose lease expired is requeued, so instances never take over each
+// other's live jobs.
+type ImportJobService struct {
+	importJobRepo *repository.ImportJobRepo
+	eventRepo     *repository.EventRepo
+	voterService  *VoterService
+	// workerID identifies this process in job leases.
+	workerID string
+
+	mu      sync.Mutex
+	running map[string]bool // eventID -> worker active
+}
+
+func NewImportJobService(
+	importJobRepo *repository.ImportJobRepo,
+	eventRepo *repository.EventRepo,
+	voterService *VoterService,
+) *ImportJobService {
+	return &ImportJobService{
+		importJobRepo: importJobRepo,
+		eventRepo:     eventRepo,
+		voterService:  voterService,
+		workerID:      newWorkerID(),
+		running:       make(map[string]bool),
+	}
+}
+
+// newWorkerID names this process: host, pid and a random suffix, so a
+// restarted process never passes for its predecessor.
+func newWorkerID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Enqueue stores the upload as a QUEUED job and starts the event's worker.
+func (s *ImportJobService) Enqueue(ctx context.Context, eventID, userID string, mode model.ImportMode, disableMissing bool, file repository.ImportJobFile) (*model.ImportJob, error) {
+	if _, err := s.voterService.importableEvent(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.importJobRepo.Create(ctx, eventID, userID, mode, disableMissing, file)
+	if err != nil {
+		return nil, err
+	}
+
+	s.runAsync(eventID)
+	return job, nil
+}
+
+func (s *ImportJobService) Get(ctx context.Context, eventID, jobID, userID string) (*model.ImportJob, error) {
+	if err := s.checkOwner(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.importJobRepo.GetByID(ctx, jobID)
+	if err != nil || job.EventID != eventID {
+		return nil, ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+func (s *ImportJobService) List(ctx context.Context, eventID, userID string) ([]model.ImportJob, error) {
+	if err := s.checkOwner(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.importJobRepo.ListByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if jobs == nil {
+		jobs = []model.ImportJob{}
+	}
+	return jobs, nil
+}
+
+func (s *ImportJobService) checkOwner(ctx context.Context, eventID, userID string) error {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return ErrEventForbidden
+	}
+	return nil
+}
+
+// ResumeQueued requeues jobs whose worker stopped and starts workers for every
+// event with queued jobs, then repeats that every importLeaseTTL until ctx is
+// done, picking up jobs left behind by instances that stop later. Called once
+// at startup.
+func (s *ImportJobService) ResumeQueued(ctx context.Context) error {
+	if err := s.resume(ctx); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(importLeaseTTL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := s.resume(ctx); err != nil {
+				log.Printf("[import] resume: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *ImportJobService) resume(ctx context.Context) error {
+	if _, err := s.importJobRepo.RequeueExpired(ctx, importLeaseTTL); err != nil {
+		return err
+	}
+	eventIDs, err := s.importJobRepo.ListQueuedEvents(ctx)
+	if err != nil {
+		return err
+	}
+	for _, eventID := range eventIDs {
+		s.runAsync(eventID)
+	}
+	return nil
+}
+
+// runAsync starts a background worker for the event unless one is already
+// running; a running worker picks up newly queued jobs itself.
+func (s *ImportJobService) runAsync(eventID string) {
+	s.mu.Lock()
+	if s.running[eventID] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[eventID] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, eventID)
+			s.mu.Unlock()
+		}()
+		if err := s.work(context.Background(), eventID); err != nil {
+			log.Printf("[import] event %s: %v", eventID, err)
+		}
+	}()
+}
+
+// work runs the event's queued jobs in order until none are left.
+func (s *ImportJobService) work(ctx context.Context, eventID string) error {
+	for {
+		job, file, err := s.importJobRepo.ClaimNext(ctx, eventID, s.workerID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.runLeased(ctx, job, file); err != nil {
+			log.Printf("[import] job %s failed: %v", job.ID, err)
+			if err := s.importJobRepo.MarkFailed(ctx, job.ID, s.workerID, err.Error()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// runLeased runs the job while renewing its lease. When the lease is lost the
+// run is cancelled, rolling its transaction back, since the job may already be
+// queued again.
+func (s *ImportJobService) runLeased(ctx context.Context, job *model.ImportJob, file *repository.ImportJobFile) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(importHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			held, err := s.importJobRepo.Heartbeat(ctx, job.ID, s.workerID)
+			if err != nil {
+				log.Printf("[import] job %s heartbeat: %v", job.ID, err)
+				continue
+			}
+			if !held {
+				log.Printf("[import] job %s lost its lease, cancelling", job.ID)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return s.run(runCtx, job, file)
+}
+
+func (s *ImportJobService) run(ctx context.Context, job *model.ImportJob, file *repository.ImportJobFile) error {
+	event, err := s.eventRepo.GetByID(ctx, job.EventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+	if event.Status == model.EventStatusLocked {
+		return ErrEventLocked
+	}
+
+	upload := RosterUpload{File: bytes.NewReader(file.Data), XLSX: file.XLSX, Sheet: file.Sheet}
+	plan, err := s.voterService.planRosterFor(ctx, event, upload)
+	if err != nil {
+		return err
+	}
+	rejected := plan.rejected
+	if rejected == nil {
+		rejected = []dto.ImportReject{}
+	}
+	rejectedJSON, _ := json.Marshal(rejected)
+	if err := s.importJobRepo.SetPlanned(ctx, job.ID, s.workerID, plan.totalRows(), rejectedJSON); err != nil {
+		return err
+	}
+
+	progress := func(processed int) {
+		if err := s.importJobRepo.SetProgress(ctx, job.ID, s.workerID, processed); err != nil {
+			log.Printf("[import] job %s progress: %v", job.ID, err)
+		}
+	}
+
+	// The owner is recorded as the actor: only they can queue jobs, and the
+	// job's creator reference may have been cleared since.
+	var result interface{}
+	switch job.Mode {
+	case model.ImportModeSync:
+		var r *dto.ImportSyncResult
+		r, err = s.voterService.applySync(ctx, plan, event.OwnerUserID, job.DisableMissing, progress)
+		if err == nil {
+			result, rejected = r, r.Rejected
+		}
+	default:
+		var r *dto.ImportResult
+		r, err = s.voterService.applyImport(ctx, plan, event.OwnerUserID, progress)
+		if err == nil {
+			result, rejected = r, r.Rejected
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	rejectedJSON, _ = json.Marshal(rejected)
+	return s.importJobRepo.MarkSucceeded(ctx, job.ID, s.workerID, rejectedJSON, resultJSON)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/config"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+	ErrLockoutNotFound = errors.New("lockout not found")
+)
+
+// lockoutPruneInterval is how often stale failure records are deleted.
+const lockoutPruneInterval = time.Hour
+
+// voteAttempt is the token/NIM pair a public voter typed in, already normalized.
+type voteAttempt struct {
+	Token string
+	NIM   string
+}
+
+// LockoutService tracks failed public voting attempts per token and per NIM so
+// guessing is throttled across IPs, not only per client.
+type LockoutService struct {
+	lockoutRepo  *repository.LockoutRepo
+	eventRepo    *repository.EventRepo
+	auditLogRepo *repository.AuditLogRepo
+	cfg          *config.Config
+}
+
+func NewLockoutService(lockoutRepo *repository.LockoutRepo, eventRepo *repository.EventRepo, auditLogRepo *repository.AuditLogRepo, cfg *config.Config) *LockoutService {
+	return &LockoutService{lockoutRepo: lockoutRepo, eventRepo: eventRepo, auditLogRepo: auditLogRepo, cfg: cfg}
+}
+
+// check rejects the attempt while its token or NIM is locked.
+func (s *LockoutService) check(ctx context.Context, eventID string, a voteAttempt) error {
+	until, err := s.lockoutRepo.LockedUntil(ctx, eventID, a.Token, a.NIM)
+	if err != nil {
+		return err
+	}
+	if until != nil {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+// settle records the outcome of a checked attempt. Only wrong credentials
+// count as failures; a success clears the counters.
+func (s *LockoutService) settle(ctx context.Context, eventID string, a voteAttempt, outcome error) {
+	switch outcome {
+	case nil:
+		if err := s.lockoutRepo.ResetFailures(ctx, eventID, a.Token, a.NIM); err != nil {
+			log.Printf("[lockout] event %s: reset failures: %v", eventID, err)
+		}
+	case ErrInvalidToken:
+		s.recordFailure(ctx, eventID, model.LockoutKindToken, a.Token)
+		s.recordFailure(ctx, eventID, model.LockoutKindNIM, a.NIM)
+	}
+}
+
+func (s *LockoutService) recordFailure(ctx context.Context, eventID string, kind model.LockoutKind, subject string) {
+	if subject == "" {
+		return
+	}
+
+	l, err := s.lockoutRepo.RecordFailure(ctx, eventID, kind, subject, s.cfg.VoteLockoutMax)
+	if err != nil {
+		log.Printf("[lockout] event %s: record failure: %v", eventID, err)
+		return
+	}
+	if l == nil || l.Failures < s.cfg.VoteLockoutThreshold {
+		return
+	}
+
+	until := time.Now().Add(s.backoff(l.Lockouts))
+	if err := s.lockoutRepo.Lock(ctx, l.ID, until); err != nil {
+		log.Printf("[lockout] event %s: lock: %v", eventID, err)
+		return
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"lockout_id":   l.ID,
+		"kind":         kind,
+		"subject":      displaySubject(kind, subject),
+		"lockouts":     l.Lockouts + 1,
+		"locked_until": until,
+	})
+	s.auditLogRepo.Create(ctx, eventID, nil, "vote.lockout", string(meta))
+}
+
+// backoff doubles the lock period for every previous lockout, up to the maximum.
+func (s *LockoutService) backoff(previous int) time.Duration {
+	d := s.cfg.VoteLockoutBase
+	for i := 0; i < previous && d < s.cfg.VoteLockoutMax; i++ {
+		d *= 2
+	}
+	if d > s.cfg.VoteLockoutMax {
+		d = s.cfg.VoteLockoutMax
+	}
+	return d
+}
+
+// StartPruning deletes stale failure records at once and then every
+// lockoutPruneInterval until ctx is done.
+func (s *LockoutService) StartPruning(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(lockoutPruneInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.lockoutRepo.PruneStale(ctx, s.cfg.VoteLockoutMax); err != nil {
+				log.Printf("[lockout] prune: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *LockoutService) List(ctx context.Context, eventID, userID string, activeOnly bool) ([]model.VoteLockout, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+
+	lockouts, err := s.lockoutRepo.ListByEvent(ctx, eventID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	if lockouts == nil {
+		lockouts = []model.VoteLockout{}
+	}
+	for i := range lockouts {
+		lockouts[i].Subject = displaySubject(lockouts[i].Kind, lockouts[i].Subject)
+	}
+	return lockouts, nil
+}
+
+// Clear removes a lockout, e.g. after the admin has verified the voter in person.
+func (s *LockoutService) Clear(ctx context.Context, eventID, lockoutID, userID string) error {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return ErrEventForbidden
+	}
+
+	l, err := s.lockoutRepo.Delete(ctx, eventID, lockoutID)
+	if err != nil {
+		return ErrLockoutNotFound
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"lockout_id": l.ID,
+		"kind":       l.Kind,
+		"subject":    displaySubject(l.Kind, l.Subject),
+	})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "vote.lockout_cleared", string(meta))
+	return nil
+}
+
+// displaySubject masks token subjects so lockout listings and audit entries
+// never reveal a token that may be valid. NIMs are shown as-is.
+func displaySubject(kind model.LockoutKind, subject string) string {
+	if kind != model.LockoutKindToken || len(subject) <= 4 {
+		return subject
+	}
+	return subject[:2] + strings.Repeat("*", len(subject)-4) + subject[len(subject)-2:]
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+)
+
+var (
+	ErrProxiesDisabled      = errors.New("proxy voting is disabled for this event")
+	ErrProxyNotFound        = errors.New("proxy not found")
+	ErrProxyNotActive       = errors.New("proxy is no longer active")
+	ErrProxySelf            = errors.New("a voter cannot hold their own proxy")
+	ErrProxyExists          = errors.New("voter has already delegated their vote")
+	ErrProxyChain           = errors.New("a voter who delegated their vote cannot hold proxies")
+	ErrProxyCapReached      = errors.New("proxy holder has reached the proxy cap")
+	ErrProxyVoterIneligible = errors.New("both voters must be eligible and the grantor must not have voted")
+	ErrProxyGrantorVoted    = errors.New("the member this proxy is for has already voted")
+)
+
+// ProxyService manages vote delegations recorded by the committee. Casting a
+// delegated ballot happens in VoteService.Submit.
+type ProxyService struct {
+	db           *sql.DB
+	proxyRepo    *repository.ProxyRepo
+	voterRepo    *repository.VoterRepo
+	eventRepo    *repository.EventRepo
+	auditLogRepo *repository.AuditLogRepo
+}
+
+func NewProxyService(
+	db *sql.DB,
+	proxyRepo *repository.ProxyRepo,
+	voterRepo *repository.VoterRepo,
+	eventRepo *repository.EventRepo,
+	auditLogRepo *repository.AuditLogRepo,
+) *ProxyService {
+	return &ProxyService{
+		db:           db,
+		proxyRepo:    proxyRepo,
+		voterRepo:    voterRepo,
+		eventRepo:    eventRepo,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// Create records that the grantor's vote is held by the holder. A member can
+// delegate once, cannot pass on a delegation, and a holder may represent at
+// most the event's proxy cap.
+func (s *ProxyService) Create(ctx context.Context, eventID, userID string, req dto.CreateProxyRequest) (*model.Proxy, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return nil, ErrEventLocked
+	}
+	if req.GrantorVoterID == req.HolderVoterID {
+		return nil, ErrProxySelf
+	}
+
+	grantor, err := s.voterRepo.GetByID(ctx, req.GrantorVoterID)
+	if err != nil || grantor.EventID != eventID {
+		return nil, ErrVoterNotFound
+	}
+	holder, err := s.voterRepo.GetByID(ctx, req.HolderVoterID)
+	if err != nil || holder.EventID != eventID {
+		return nil, ErrVoterNotFound
+	}
+	if grantor.Status != model.VoterStatusEligible || grantor.HasVoted || holder.Status != model.VoterStatusEligible {
+		return nil, ErrProxyVoterIneligible
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The event row lock serializes concurrent grants against the cap.
+	event, err = s.eventRepo.LockForUpdate(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.ProxyCap == 0 {
+		return nil, ErrProxiesDisabled
+	}
+
+	delegated, err := s.proxyRepo.HasLiveAsGrantor(ctx, tx, grantor.ID)
+	if err != nil {
+		return nil, err
+	}
+	if delegated {
+		return nil, ErrProxyExists
+	}
+	holderDelegated, err := s.proxyRepo.HasLiveAsGrantor(ctx, tx, holder.ID)
+	if err != nil {
+		return nil, err
+	}
+	if holderDelegated {
+		return nil, ErrProxyChain
+	}
+	grantorHolds, err := s.proxyRepo.CountLiveByHolder(ctx, tx, grantor.ID)
+	if err != nil {
+		return nil, err
+	}
+	if grantorHolds > 0 {
+		return nil, ErrProxyChain
+	}
+	held, err := s.proxyRepo.CountLiveByHolder(ctx, tx, holder.ID)
+	if err != nil {
+		return nil, err
+	}
+	if held >= event.ProxyCap {
+		return nil, ErrProxyCapReached
+	}
+
+	proxy, err := s.proxyRepo.CreateInTx(ctx, tx, eventID, grantor.ID, holder.ID, req.Note, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]string{
+		"proxy_id":         proxy.ID,
+		"grantor_voter_id": grantor.ID,
+		"holder_voter_id":  holder.ID,
+	})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "proxy.created", string(meta))
+	return proxy, nil
+}
+
+func (s *ProxyService) List(ctx context.Context, eventID, userID string) ([]repository.ProxyListRow, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+
+	proxies, err := s.proxyRepo.ListByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if proxies == nil {
+		proxies = []repository.ProxyListRow{}
+	}
+	return proxies, nil
+}
+
+// Revoke cancels a proxy that has not been used yet.
+func (s *ProxyService) Revoke(ctx context.Context, eventID, proxyID, userID string) error {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return ErrEventLocked
+	}
+
+	revoked, err := s.proxyRepo.Revoke(ctx, eventID, proxyID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrProxyNotActive
+	}
+
+	meta, _ := json.Marshal(map[string]string{"proxy_id": proxyID})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "proxy.revoked", string(meta))
+	return nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/amard/pemilo-golang/internal/config"
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/util"
+)
+
+var (
+	ErrRegistrationClosed = errors.New("registration is not open for this event")
+	ErrAlreadyRegistered  = errors.New("this NIM is already registered")
+	ErrContactRequired    = errors.New("email or phone is required")
+)
+
+// RegistrationService handles voter self-registration: a public sign-up queue
+// that the committee reviews before entries join the roster.
+type RegistrationService struct {
+	db               *sql.DB
+	registrationRepo *repository.RegistrationRepo
+	voterRepo        *repository.VoterRepo
+	voterTokenRepo   *repository.VoterTokenRepo
+	eventRepo        *repository.EventRepo
+	auditLogRepo     *repository.AuditLogRepo
+	cfg              *config.Config
+}
+
+func NewRegistrationService(
+	db *sql.DB,
+	registrationRepo *repository.RegistrationRepo,
+	voterRepo *repository.VoterRepo,
+	voterTokenRepo *repository.VoterTokenRepo,
+	eventRepo *repository.EventRepo,
+	auditLogRepo *repository.AuditLogRepo,
+	cfg *config.Config,
+) *RegistrationService {
+	return &RegistrationService{
+		db:               db,
+		registrationRepo: registrationRepo,
+		voterRepo:        voterRepo,
+		voterTokenRepo:   voterTokenRepo,
+		eventRepo:        eventRepo,
+		auditLogRepo:     auditLogRepo,
+		cfg:              cfg,
+	}
+}
+
+// acceptsRegistrations reports whether the public may currently sign up.
+func acceptsRegistrations(event *model.Event) bool {
+	return event.RegistrationOpen && event.Status != model.EventStatusClosed && event.Status != model.EventStatusLocked
+}
+
+// Register adds a PENDING entry to the event's registration queue.
+func (s *RegistrationService) Register(ctx context.Context, eventID string, req dto.VoterRegistrationRequest) (*model.VoterRegistration, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if !acceptsRegistrations(event) {
+		return nil, ErrRegistrationClosed
+	}
+
+	row, dob, err := voterInputRow(nimPolicy(event), req.FullName, req.NIM, req.ClassName, req.Email, req.Phone, req.DOB)
+	if err != nil {
+		return nil, err
+	}
+	if row.Email == "" && row.Phone == "" {
+		return nil, ErrContactRequired
+	}
+	if err := applySecondFactor(s.cfg.SecondFactorKey, event, &row, dob); err != nil {
+		return nil, err
+	}
+
+	onRoster, err := s.voterRepo.ExistsByEventAndNIM(ctx, eventID, row.NIMNormalized)
+	if err != nil {
+		return nil, err
+	}
+	if onRoster {
+		return nil, ErrAlreadyRegistered
+	}
+
+	reg, err := s.registrationRepo.Create(ctx, eventID, row)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlreadyRegistered
+	}
+	return reg, err
+}
+
+func (s *RegistrationService) List(ctx context.Context, eventID, userID, status string) ([]model.VoterRegistration, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+
+	regs, err := s.registrationRepo.ListByEvent(ctx, eventID, status)
+	if err != nil {
+		return nil, err
+	}
+	if regs == nil {
+		regs = []model.VoterRegistration{}
+	}
+	return regs, nil
+}
+
+// Approve moves PENDING registrations onto the roster in one transaction. The
+// whole batch is refused if it would exceed the package's voter limit. Entries
+// whose NIM reached the roster some other way stay PENDING and are reported.
+func (s *RegistrationService) Approve(ctx context.Context, eventID, userID string, req dto.ApproveRegistrationsRequest) (*dto.RegistrationReviewResult, error) {
+	if err := s.checkEditable(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The event row lock serializes concurrent approvals against MaxVoters.
+	event, err := s.eventRepo.LockForUpdate(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.registrationRepo.LockPending(ctx, tx, eventID, req.IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.voterRepo.CountByEventInTx(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if count+len(pending) > event.MaxVoters {
+		return nil, ErrMaxVotersReached
+	}
+
+	result := &dto.RegistrationReviewResult{Skipped: notPendingSkips(req.IDs, pending)}
+	policy := tokenPolicy(event)
+	for _, reg := range pending {
+		voter, err := s.voterRepo.CreateInTx(ctx, tx, eventID, registrationInsertRow(s.cfg.SecondFactorKey, reg))
+		if err == sql.ErrNoRows {
+			result.Skipped = append(result.Skipped, dto.RegistrationSkip{ID: reg.ID, Reason: "nim already on roster"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.registrationRepo.MarkApproved(ctx, tx, reg.ID, voter.ID, userID); err != nil {
+			return nil, err
+		}
+		result.ReviewedCount++
+
+		if req.IssueTokens {
+			token, err := util.GenerateToken(policy)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := s.voterTokenRepo.CreateInTx(ctx, tx, eventID, voter.ID, token); err != nil {
+				return nil, err
+			}
+			result.TokensIssued++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"approved":      result.ReviewedCount,
+		"tokens_issued": result.TokensIssued,
+		"skipped":       len(result.Skipped),
+	})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "registrations.approved", string(meta))
+	return result, nil
+}
+
+// Reject closes PENDING registrations without adding them to the roster.
+func (s *RegistrationService) Reject(ctx context.Context, eventID, userID string, req dto.RejectRegistrationsRequest) (*dto.RegistrationReviewResult, error) {
+	if err := s.checkEditable(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pending, err := s.registrationRepo.LockPending(ctx, tx, eventID, req.IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.RegistrationReviewResult{Skipped: notPendingSkips(req.IDs, pending)}
+	for _, reg := range pending {
+		if err := s.registrationRepo.MarkRejected(ctx, tx, reg.ID, userID, req.Reason); err != nil {
+			return nil, err
+		}
+		result.ReviewedCount++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"rejected": result.ReviewedCount, "reason": req.Reason})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "registrations.rejected", string(meta))
+	return result, nil
+}
+
+func (s *RegistrationService) checkEditable(ctx context.Context, eventID, userID string) error {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return ErrEventLocked
+	}
+	return nil
+}
+
+// notPendingSkips reports requested ids that were not found as PENDING.
+func notPendingSkips(ids []string, pending []model.VoterRegistration) []dto.RegistrationSkip {
+	found := make(map[string]bool, len(pending))
+	for _, reg := range pending {
+		found[reg.ID] = true
+	}
+
+	skipped := []dto.RegistrationSkip{}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.ToLower(id)
+		if found[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		skipped = append(skipped, dto.RegistrationSkip{ID: id, Reason: "not found or already reviewed"})
+	}
+	return skipped
+}
+
+// registrationInsertRow turns an approved registration into a roster row. The
+// phone hash is computed here since registrations do not store it.
+func registrationInsertRow(key string, reg model.VoterRegistration) repository.VoterInsertRow {
+	row := repository.VoterInsertRow{
+		FullName:      reg.FullName,
+		NIMRaw:        reg.NIMRaw,
+		NIMNormalized: reg.NIMNormalized,
+	}
+	if reg.ClassName != nil {
+		row.ClassName = *reg.ClassName
+	}
+	if reg.Email != nil {
+		row.Email = *reg.Email
+	}
+	if reg.Phone != nil {
+		row.Phone = *reg.Phone
+		row.PhoneLast4Hash = phoneLast4Hash(key, reg.EventID, row.Phone)
+	}
+	if reg.DOBHash != nil {
+		row.DOBHash = *reg.DOBHash
+	}
+	return row
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/amard/pemilo-golang/internal/repository"
+)
+
+// schedulerLockKey is the Postgres advisory lock held while a scheduler pass
+// runs, so only one server instance moves events at a time.
+const schedulerLockKey = 0x70656d696c6f01
+
+// scheduledMeta marks audit entries written by the scheduler rather than an
+// organizer.
+const scheduledMeta = `{"trigger":"schedule"}`
+
+// SchedulerService moves events along their schedule: drafts with a future
+// opens_at become SCHEDULED, scheduled events open at opens_at and open events
+// close at closes_at.
+type SchedulerService struct {
+	db           *sql.DB
+	eventRepo    *repository.EventRepo
+	auditLogRepo *repository.AuditLogRepo
+	interval     time.Duration
+}
+
+func NewSchedulerService(db *sql.DB, eventRepo *repository.EventRepo, auditLogRepo *repository.AuditLogRepo, interval time.Duration) *SchedulerService {
+	return &SchedulerService{db: db, eventRepo: eventRepo, auditLogRepo: auditLogRepo, interval: interval}
+}
+
+// Start runs a pass at once and then every interval until ctx is done.
+func (s *SchedulerService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.RunOnce(ctx); err != nil {
+				log.Printf("scheduler: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce applies every transition that is due. It does nothing when another
+// instance holds the scheduler lock.
+func (s *SchedulerService) RunOnce(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, int64(schedulerLockKey)).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	now := time.Now()
+	scheduled, err := s.eventRepo.MarkScheduled(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+	// Opening before closing lets an event whose whole window has passed go
+	// through both, with both audit entries.
+	opened, err := s.eventRepo.OpenDue(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+	closed, err := s.eventRepo.CloseDue(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range []struct {
+		action string
+		ids    []string
+	}{
+		{"event.scheduled", scheduled},
+		{"event.opened", opened},
+		{"event.closed", closed},
+	} {
+		for _, id := range step.ids {
+			if err := s.auditLogRepo.CreateInTx(ctx, tx, id, nil, step.action, scheduledMeta); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"github.com/amard/pemilo-golang/internal/dto"
@@ -47,6 +48,29 @@ func (s *StatsService) GetStats(ctx context.Context, eventID, userID string) (*d
 		latestVoters = []dto.LatestVoter{}
 	}
 
+	turnoutByClass, err := s.ballotRepo.GetTurnoutByClass(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if turnoutByClass == nil {
+		turnoutByClass = []dto.ClassTurnout{}
+	}
+	for i := range turnoutByClass {
+		turnoutByClass[i].TurnoutPercent = turnoutPercent(turnoutByClass[i].VotedCount, turnoutByClass[i].TotalVoters)
+	}
+
+	turnoutByAttribute := make(map[string][]dto.TurnoutSegment, len(event.VoterAttributes))
+	for _, key := range event.VoterAttributes {
+		segments, err := s.ballotRepo.GetTurnoutByAttribute(ctx, eventID, key)
+		if err != nil {
+			return nil, err
+		}
+		if segments == nil {
+			segments = []dto.TurnoutSegment{}
+		}
+		turnoutByAttribute[key] = segments
+	}
+
 	return &dto.StatsResponse{
 		EventID:       eventID,
 		TotalVoters:   total,
@@ -55,5 +79,50 @@ func (s *StatsService) GetStats(ctx context.Context, eventID, userID string) (*d
 		VotesBySlate:  votesBySlate,
 		LatestVoters:  latestVoters,
 		UpdatedAt:     time.Now(),
+
+		TurnoutByClass:     turnoutByClass,
+		TurnoutByAttribute: turnoutByAttribute,
 	}, nil
 }
+
+// ListNotVoted returns a page of voters who have not voted yet, optionally for
+// one class, so class coordinators can follow up.
+func (s *StatsService) ListNotVoted(ctx context.Context, eventID, userID string, className *string, page, perPage int) (*dto.NotVotedListResponse, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+
+	if perPage <= 0 || perPage > 100 {
+		perPage = 50
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	voters, total, err := s.ballotRepo.GetNotVoted(ctx, eventID, className, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, err
+	}
+	if voters == nil {
+		voters = []dto.NotVotedVoter{}
+	}
+
+	return &dto.NotVotedListResponse{
+		Voters:  voters,
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	}, nil
+}
+
+// turnoutPercent is voted/total as a percentage rounded to one decimal.
+func turnoutPercent(voted, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(voted)*1000/float64(total)) / 10
+}
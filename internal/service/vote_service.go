@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
+	"github.com/amard/pemilo-golang/internal/config"
 	"github.com/amard/pemilo-golang/internal/dto"
 	"github.com/amard/pemilo-golang/internal/model"
 	"github.com/amard/pemilo-golang/internal/repository"
@@ -27,6 +29,10 @@ type VoteService struct {
 	voterRepo      *repository.VoterRepo
 	voterTokenRepo *repository.VoterTokenRepo
 	ballotRepo     *repository.BallotRepo
+	proxyRepo      *repository.ProxyRepo
+	auditLogRepo   *repository.AuditLogRepo
+	lockouts       *LockoutService
+	cfg            *config.Config
 }
 
 func NewVoteService(
@@ -36,6 +42,10 @@ func NewVoteService(
 	voterRepo *repository.VoterRepo,
 	voterTokenRepo *repository.VoterTokenRepo,
 	ballotRepo *repository.BallotRepo,
+	proxyRepo *repository.ProxyRepo,
+	auditLogRepo *repository.AuditLogRepo,
+	lockouts *LockoutService,
+	cfg *config.Config,
 ) *VoteService {
 	return &VoteService{
 		db:             db,
@@ -44,10 +54,14 @@ func NewVoteService(
 		voterRepo:      voterRepo,
 		voterTokenRepo: voterTokenRepo,
 		ballotRepo:     ballotRepo,
+		proxyRepo:      proxyRepo,
+		auditLogRepo:   auditLogRepo,
+		lockouts:       lockouts,
+		cfg:            cfg,
 	}
 }
 
-func (s *VoteService) Prepare(ctx context.Context, eventID string, req dto.VotePrepareRequest) (*dto.VotePrepareResponse, error) {
+func (s *VoteService) Prepare(ctx context.Context, eventID string, req dto.VotePrepareRequest) (resp *dto.VotePrepareResponse, err error) {
 	// Validate event
 	event, err := s.eventRepo.GetByID(ctx, eventID)
 	if err != nil {
@@ -58,37 +72,109 @@ func (s *VoteService) Prepare(ctx context.Context, eventID string, req dto.VoteP
 		return nil, ErrEventNotOpen
 	}
 
-	// Normalize inputs
-	token := util.NormalizeToken(req.Token)
-	if !util.ValidateToken(token) {
+	// Normalize inputs — malformed tokens never reach the database
+	policy := tokenPolicy(event)
+	token := util.NormalizeToken(req.Token, policy)
+	if !util.ValidateToken(token, policy) {
 		return nil, ErrInvalidToken
 	}
-	nimNorm := util.NormalizeNIM(req.NIM)
+	nimNorm := nimPolicy(event).Normalize(req.NIM)
+	if !nimPolicy(event).Matches(nimNorm) {
+		return nil, ErrNIMFormat
+	}
+
+	// Throttle guessing per token and per NIM, across all clients
+	attempt := voteAttempt{Token: token, NIM: nimNorm}
+	if err := s.lockouts.check(ctx, eventID, attempt); err != nil {
+		return nil, err
+	}
+	defer func() { s.lockouts.settle(ctx, eventID, attempt, err) }()
 
 	// Find token
 	vt, err := s.voterTokenRepo.GetByEventAndToken(ctx, eventID, token)
-	if err != nil {
+	if err != nil || vt.Status == model.TokenStatusRevoked {
 		return nil, ErrInvalidToken // generic error
 	}
 
-	if vt.Status != model.TokenStatusActive {
-		return nil, ErrAlreadyVoted
-	}
-
 	// Find voter and verify NIM match
 	voter, err := s.voterRepo.GetByEventAndNIM(ctx, eventID, nimNorm)
 	if err != nil || voter.ID != vt.VoterID {
 		return nil, ErrInvalidToken // generic error
 	}
 
+	if !s.checkSecondFactor(event, voter, req.SecondFactor) {
+		return nil, ErrInvalidToken
+	}
+
 	if voter.Status != model.VoterStatusEligible {
 		return nil, ErrVoterNotEligible
 	}
 
-	if voter.HasVoted {
+	return s.openSession(ctx, eventID, voter, vt.Status != model.TokenStatusActive)
+}
+
+// PrepareLink opens a voting session from a magic link. A valid link stands
+// in for the voter's token and NIM; the event's second factor is still
+// required, and wrong answers count against the token's lockout.
+func (s *VoteService) PrepareLink(ctx context.Context, eventID string, req dto.VoteLinkRequest) (resp *dto.VotePrepareResponse, err error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+
+	if !s.isEventOpen(event) {
+		return nil, ErrEventNotOpen
+	}
+
+	vt, err := s.resolveLink(ctx, eventID, req.Link)
+	if err != nil {
+		return nil, err
+	}
+
+	// A revoked token kills its link
+	if vt.Status == model.TokenStatusRevoked {
+		return nil, ErrInvalidToken
+	}
+
+	attempt := voteAttempt{Token: vt.Token}
+	if err := s.lockouts.check(ctx, eventID, attempt); err != nil {
+		return nil, err
+	}
+	defer func() { s.lockouts.settle(ctx, eventID, attempt, err) }()
+
+	voter, err := s.voterRepo.GetByID(ctx, vt.VoterID)
+	if err != nil || voter.EventID != eventID {
+		return nil, ErrInvalidToken
+	}
+
+	if !s.checkSecondFactor(event, voter, req.SecondFactor) {
+		return nil, ErrInvalidToken
+	}
+
+	if voter.Status != model.VoterStatusEligible {
+		return nil, ErrVoterNotEligible
+	}
+
+	return s.openSession(ctx, eventID, voter, vt.Status != model.TokenStatusActive)
+}
+
+// openSession builds the ballot shown to a verified voter. A voter whose own
+// ballot is cast still gets a session while they hold unused proxies.
+func (s *VoteService) openSession(ctx context.Context, eventID string, voter *model.Voter, tokenUsed bool) (*dto.VotePrepareResponse, error) {
+	open, err := s.proxyRepo.ListOpenByHolder(ctx, voter.ID)
+	if err != nil {
+		return nil, err
+	}
+	hasVoted := voter.HasVoted || tokenUsed
+	if hasVoted && len(open) == 0 {
 		return nil, ErrAlreadyVoted
 	}
 
+	proxies := make([]dto.ProxyBallot, len(open))
+	for i, p := range open {
+		proxies[i] = dto.ProxyBallot{ProxyID: p.ProxyID, FullName: p.FullName, ClassName: p.ClassName}
+	}
+
 	// Fetch slates with members
 	slates, err := s.slateRepo.ListByEvent(ctx, eventID)
 	if err != nil {
@@ -132,10 +218,12 @@ func (s *VoteService) Prepare(ctx context.Context, eventID string, req dto.VoteP
 		},
 		Slates:    slatesPublic,
 		ExpiresAt: time.Now().Add(15 * time.Minute),
+		HasVoted:  hasVoted,
+		Proxies:   proxies,
 	}, nil
 }
 
-func (s *VoteService) Submit(ctx context.Context, eventID string, req dto.VoteSubmitRequest) error {
+func (s *VoteService) Submit(ctx context.Context, eventID string, req dto.VoteSubmitRequest) (err error) {
 	// Validate event
 	event, err := s.eventRepo.GetByID(ctx, eventID)
 	if err != nil {
@@ -145,12 +233,38 @@ func (s *VoteService) Submit(ctx context.Context, eventID string, req dto.VoteSu
 		return ErrEventNotOpen
 	}
 
-	// Normalize inputs
-	token := util.NormalizeToken(req.Token)
-	if !util.ValidateToken(token) {
-		return ErrInvalidToken
+	// Normalize inputs — a magic link stands in for token + NIM
+	var token, nimNorm, linkVoterID string
+	if req.Link != "" {
+		linked, linkErr := s.resolveLink(ctx, eventID, req.Link)
+		if linkErr != nil {
+			return linkErr
+		}
+		token = linked.Token
+		linkVoterID = linked.VoterID
+
+		attempt := voteAttempt{Token: token}
+		if err := s.lockouts.check(ctx, eventID, attempt); err != nil {
+			return err
+		}
+		defer func() { s.lockouts.settle(ctx, eventID, attempt, err) }()
+	} else {
+		policy := tokenPolicy(event)
+		token = util.NormalizeToken(req.Token, policy)
+		if !util.ValidateToken(token, policy) {
+			return ErrInvalidToken
+		}
+		nimNorm = nimPolicy(event).Normalize(req.NIM)
+		if !nimPolicy(event).Matches(nimNorm) {
+			return ErrNIMFormat
+		}
+
+		attempt := voteAttempt{Token: token, NIM: nimNorm}
+		if err := s.lockouts.check(ctx, eventID, attempt); err != nil {
+			return err
+		}
+		defer func() { s.lockouts.settle(ctx, eventID, attempt, err) }()
 	}
-	nimNorm := util.NormalizeNIM(req.NIM)
 
 	// Verify slate exists for this event
 	slate, err := s.slateRepo.GetByID(ctx, req.SlateID)
@@ -167,20 +281,36 @@ func (s *VoteService) Submit(ctx context.Context, eventID string, req dto.VoteSu
 
 	// 1) Lock token row FOR UPDATE
 	vt, err := s.voterTokenRepo.LockForUpdate(ctx, tx, eventID, token)
-	if err != nil {
+	if err != nil || vt.Status == model.TokenStatusRevoked {
 		return ErrInvalidToken
 	}
 
-	// 2) Verify token is ACTIVE
-	if vt.Status != model.TokenStatusActive {
+	// 2) Verify token is ACTIVE — a proxy holder may have used theirs already
+	if vt.Status != model.TokenStatusActive && req.ProxyID == "" {
 		return ErrAlreadyVoted
 	}
 
 	// 3) Fetch voter via token's voter_id, verify NIM match
-	voter, err := s.voterRepo.GetByEventAndNIM(ctx, eventID, nimNorm)
+	var voter *model.Voter
+	if linkVoterID != "" {
+		voter, err = s.voterRepo.GetByID(ctx, linkVoterID)
+	} else {
+		voter, err = s.voterRepo.GetByEventAndNIM(ctx, eventID, nimNorm)
+	}
 	if err != nil || voter.ID != vt.VoterID {
 		return ErrInvalidToken
 	}
+	if !s.checkSecondFactor(event, voter, req.SecondFactor) {
+		return ErrInvalidToken
+	}
+
+	if req.ProxyID != "" {
+		return s.castProxyBallot(ctx, tx, eventID, voter, req.ProxyID, req.SlateID)
+	}
+
+	if voter.Status != model.VoterStatusEligible {
+		return ErrVoterNotEligible
+	}
 
 	if voter.HasVoted {
 		return ErrAlreadyVoted
@@ -208,6 +338,105 @@ func (s *VoteService) Submit(ctx context.Context, eventID string, req dto.VoteSu
 	return tx.Commit()
 }
 
+// castProxyBallot casts the grantor's ballot for an authenticated proxy holder.
+// The grantor is marked voted through the same MarkVoted guard as a direct
+// vote, so whichever of the two comes first wins.
+func (s *VoteService) castProxyBallot(ctx context.Context, tx *sql.Tx, eventID string, holder *model.Voter, proxyID, slateID string) error {
+	if holder.Status != model.VoterStatusEligible {
+		return ErrVoterNotEligible
+	}
+
+	proxy, err := s.proxyRepo.LockForUpdate(ctx, tx, proxyID)
+	if err != nil || proxy.EventID != eventID || proxy.HolderVoterID != holder.ID {
+		return ErrProxyNotFound
+	}
+	if proxy.Status != model.ProxyStatusActive {
+		return ErrProxyNotActive
+	}
+
+	grantor, err := s.voterRepo.GetByID(ctx, proxy.GrantorVoterID)
+	if err != nil {
+		return err
+	}
+	if grantor.Status != model.VoterStatusEligible {
+		return ErrVoterNotEligible
+	}
+
+	if err := s.ballotRepo.InsertInTx(ctx, tx, eventID, slateID); err != nil {
+		return err
+	}
+
+	rowsAffected, err := s.voterRepo.MarkVoted(ctx, tx, grantor.ID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return ErrProxyGrantorVoted
+	}
+
+	// Retire the grantor's own token so it cannot be used afterwards
+	gt, err := s.voterTokenRepo.LockLiveByVoter(ctx, tx, grantor.ID)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	case gt.Status == model.TokenStatusActive:
+		if err := s.voterTokenRepo.MarkUsed(ctx, tx, gt.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.proxyRepo.MarkUsed(ctx, tx, proxy.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	meta, _ := json.Marshal(map[string]string{
+		"proxy_id":         proxy.ID,
+		"grantor_voter_id": grantor.ID,
+		"holder_voter_id":  holder.ID,
+	})
+	s.auditLogRepo.Create(ctx, eventID, nil, "proxy.ballot_cast", string(meta))
+	return nil
+}
+
+// resolveLink verifies a magic link credential and returns its token.
+func (s *VoteService) resolveLink(ctx context.Context, eventID, credential string) (*model.VoterToken, error) {
+	tokenID, err := util.ParseVoteLink(credential)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	vt, err := s.voterTokenRepo.GetByID(ctx, tokenID)
+	if err != nil || vt.EventID != eventID {
+		return nil, ErrInvalidToken
+	}
+
+	if !util.VerifyVoteLink(s.cfg.VoteLinkSecret, eventID, vt.ID, vt.Token, credential) {
+		return nil, ErrInvalidToken
+	}
+	return vt, nil
+}
+
+// checkSecondFactor verifies the event's second factor, if any. It applies to
+// magic links as well as token+NIM, since a forwarded link is as good as a
+// leaked token.
+func (s *VoteService) checkSecondFactor(event *model.Event, voter *model.Voter, value string) bool {
+	if event.SecondFactor == nil {
+		return true
+	}
+	switch *event.SecondFactor {
+	case model.SecondFactorDOB:
+		dob, ok := util.NormalizeDOB(value)
+		return ok && voter.DOBHash != nil && util.VerifySecondFactor(s.cfg.SecondFactorKey, event.ID, dob, *voter.DOBHash)
+	case model.SecondFactorPhoneLast4:
+		return voter.PhoneLast4Hash != nil && util.VerifyPhoneLast4(s.cfg.SecondFactorKey, event.ID, value, *voter.PhoneLast4Hash)
+	}
+	return false
+}
+
 func (s *VoteService) isEventOpen(event *model.Event) bool {
 	if event.Status != model.EventStatusOpen {
 		return false
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"maps"
+	"sort"
+
+	"github.com/amard/pemilo-golang/internal/dto"
+	"github.com/amard/pemilo-golang/internal/model"
+	"github.com/amard/pemilo-golang/internal/repository"
+	"github.com/amard/pemilo-golang/internal/util"
+)
+
+// RosterUpload is a voter roster file sent to the import endpoint.
+type RosterUpload struct {
+	File io.Reader
+	XLSX bool
+	// Sheet picks the workbook sheet for XLSX uploads; empty means the first.
+	Sheet string
+}
+
+func (u RosterUpload) parse(nim util.NIMPolicy) (*util.CSVParseResult, error) {
+	if u.XLSX {
+		return util.ParseVotersXLSX(u.File, u.Sheet, nim)
+	}
+	return util.ParseVotersCSV(u.File, nim)
+}
+
+// rosterPlan is an uploaded roster checked against the event's current voters.
+type rosterPlan struct {
+	event        *model.Event
+	currentCount int
+	// newRows are the accepted rows whose NIM is not on the roster yet.
+	newRows []repository.VoterInsertRow
+	// matches are accepted rows whose NIM already belongs to a voter.
+	matches  []rosterMatch
+	rejected []dto.ImportReject
+	// fileNIMs are the normalized NIMs found in the file, rejected rows
+	// included, so a sync never disables a voter whose row merely failed.
+	fileNIMs []string
+}
+
+type rosterMatch struct {
+	row   util.VoterCSVRow
+	voter model.Voter
+	// attributes are the row's values for the event's attributes.
+	attributes map[string]string
+}
+
+// unchanged reports whether the row matches the voter's name, class and
+// attributes.
+func (m rosterMatch) unchanged() bool {
+	return m.row.FullName == m.voter.FullName && m.row.ClassName == deref(m.voter.ClassName) &&
+		maps.Equal(m.attributes, m.voter.Attributes)
+}
+
+// exceedsLimit reports whether adding the new rows would go over the
+// package's voter limit.
+func (p *rosterPlan) exceedsLimit() bool {
+	return p.currentCount+len(p.newRows) > p.event.MaxVoters
+}
+
+// totalRows is the number of data rows in the file.
+func (p *rosterPlan) totalRows() int {
+	return len(p.rejected) + len(p.matches) + len(p.newRows)
+}
+
+// importProgress is told how many of the file's rows have been handled so far.
+// It may be nil.
+type importProgress func(processed int)
+
+func (f importProgress) report(processed int) {
+	if f != nil {
+		f(processed)
+	}
+}
+
+// importBatchSize is how many new voters are inserted per statement.
+const importBatchSize = 500
+
+// importableEvent loads an event the user may import voters into.
+func (s *VoterService) importableEvent(ctx context.Context, eventID, userID string) (*model.Event, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return nil, ErrEventLocked
+	}
+	return event, nil
+}
+
+// planRoster checks the event, parses the upload and splits its rows into new
+// voters, existing voters and rejects. It writes nothing.
+func (s *VoterService) planRoster(ctx context.Context, eventID, userID string, upload RosterUpload) (*rosterPlan, error) {
+	event, err := s.importableEvent(ctx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.planRosterFor(ctx, event, upload)
+}
+
+func (s *VoterService) planRosterFor(ctx context.Context, event *model.Event, upload RosterUpload) (*rosterPlan, error) {
+	eventID := event.ID
+	currentCount, err := s.voterRepo.CountByEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := upload.parse(nimPolicy(event))
+	if err != nil {
+		return nil, err
+	}
+
+	nims := make([]string, 0, len(parsed.Rows))
+	for _, row := range parsed.Rows {
+		nims = append(nims, row.NIMNormalized)
+	}
+	existing, err := s.voterRepo.ListByNIMs(ctx, eventID, nims)
+	if err != nil {
+		return nil, err
+	}
+	byNIM := make(map[string]model.Voter, len(existing))
+	for _, v := range existing {
+		byNIM[v.NIMNormalized] = v
+	}
+
+	plan := &rosterPlan{event: event, currentCount: currentCount}
+	for _, r := range parsed.Rejected {
+		plan.rejected = append(plan.rejected, dto.ImportReject{Row: r.Row, Reason: r.Reason})
+		if r.NIM != "" {
+			plan.fileNIMs = append(plan.fileNIMs, r.NIM)
+		}
+	}
+	for _, row := range parsed.Rows {
+		plan.fileNIMs = append(plan.fileNIMs, row.NIMNormalized)
+		attributes, err := eventAttributes(event, row.Attributes)
+		if err != nil {
+			plan.rejected = append(plan.rejected, dto.ImportReject{Row: row.Row, Reason: err.Error()})
+			continue
+		}
+		if v, ok := byNIM[row.NIMNormalized]; ok {
+			plan.matches = append(plan.matches, rosterMatch{row: row, voter: v, attributes: attributes})
+			continue
+		}
+		insert := repository.VoterInsertRow{
+			Row:           row.Row,
+			FullName:      row.FullName,
+			NIMRaw:        row.NIMRaw,
+			NIMNormalized: row.NIMNormalized,
+			ClassName:     row.ClassName,
+			Email:         row.Email,
+			Phone:         row.Phone,
+			Attributes:    attributes,
+		}
+		if err := applySecondFactor(s.cfg.SecondFactorKey, event, &insert, row.DOB); err != nil {
+			plan.rejected = append(plan.rejected, dto.ImportReject{Row: row.Row, Reason: err.Error()})
+			continue
+		}
+		plan.newRows = append(plan.newRows, insert)
+	}
+	return plan, nil
+}
+
+// Import adds the roster's new voters to the event in one transaction. Rows
+// whose NIM is already on the roster are rejected as duplicates.
+func (s *VoterService) Import(ctx context.Context, eventID, userID string, upload RosterUpload) (*dto.ImportResult, error) {
+	plan, err := s.planRoster(ctx, eventID, userID, upload)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyImport(ctx, plan, userID, nil)
+}
+
+func (s *VoterService) applyImport(ctx context.Context, plan *rosterPlan, userID string, progress importProgress) (*dto.ImportResult, error) {
+	eventID := plan.event.ID
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkRosterLimit(ctx, tx, eventID, len(plan.newRows)); err != nil {
+		return nil, err
+	}
+
+	// Merge file-level rejections with duplicates and DB-level rejections
+	allRejected := plan.rejected
+	for _, m := range plan.matches {
+		allRejected = append(allRejected, dto.ImportReject{Row: m.row.Row, Reason: "duplicate nim in event"})
+	}
+	processed := len(plan.rejected) + len(plan.matches)
+	progress.report(processed)
+
+	imported, dbRejected, err := s.bulkInsert(ctx, tx, eventID, plan.newRows, processed, progress)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	allRejected = append(allRejected, dbRejected...)
+	if allRejected == nil {
+		allRejected = []dto.ImportReject{}
+	}
+	sortRejects(allRejected)
+
+	s.auditLogRepo.Create(ctx, eventID, &userID, "voters.imported", `{}`)
+
+	return &dto.ImportResult{
+		ImportedCount: imported,
+		Rejected:      allRejected,
+	}, nil
+}
+
+// bulkInsert inserts rows in batches of importBatchSize, reporting progress
+// after each batch on top of the rows already processed.
+func (s *VoterService) bulkInsert(ctx context.Context, tx *sql.Tx, eventID string, rows []repository.VoterInsertRow, processed int, progress importProgress) (int, []dto.ImportReject, error) {
+	inserted := 0
+	var rejected []dto.ImportReject
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		n, batchRejected, err := s.voterRepo.BulkInsert(ctx, tx, eventID, rows[start:end])
+		if err != nil {
+			return 0, nil, err
+		}
+		inserted += n
+		rejected = append(rejected, batchRejected...)
+		progress.report(processed + end)
+	}
+	return inserted, rejected, nil
+}
+
+// checkRosterLimit locks the event and returns ErrMaxVotersReached when adding
+// more voters would exceed the package limit. The row lock serializes
+// concurrent additions until tx ends.
+func (s *VoterService) checkRosterLimit(ctx context.Context, tx *sql.Tx, eventID string, more int) error {
+	event, err := s.eventRepo.LockForUpdate(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+	count, err := s.voterRepo.CountByEventInTx(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+	if count+more > event.MaxVoters {
+		return ErrMaxVotersReached
+	}
+	return nil
+}
+
+// SyncImport reconciles the event's roster with the upload, keyed on the
+// normalized NIM: new NIMs are added, existing voters get the file's name,
+// class and attribute values, and with disableMissing eligible voters absent from the file are
+// disabled. Voters who have voted are never touched. Everything is applied in
+// one transaction.
+func (s *VoterService) SyncImport(ctx context.Context, eventID, userID string, upload RosterUpload, disableMissing bool) (*dto.ImportSyncResult, error) {
+	plan, err := s.planRoster(ctx, eventID, userID, upload)
+	if err != nil {
+		return nil, err
+	}
+	return s.applySync(ctx, plan, userID, disableMissing, nil)
+}
+
+func (s *VoterService) applySync(ctx context.Context, plan *rosterPlan, userID string, disableMissing bool, progress importProgress) (*dto.ImportSyncResult, error) {
+	eventID := plan.event.ID
+	if disableMissing && len(plan.fileNIMs) == 0 {
+		return nil, ErrEmptyRoster
+	}
+
+	result := &dto.ImportSyncResult{Rejected: plan.rejected}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkRosterLimit(ctx, tx, eventID, len(plan.newRows)); err != nil {
+		return nil, err
+	}
+	processed := len(plan.rejected)
+	progress.report(processed)
+
+	for _, m := range plan.matches {
+		if m.voter.HasVoted {
+			result.SkippedVotedCount++
+			continue
+		}
+		if m.unchanged() {
+			result.UnchangedCount++
+			continue
+		}
+		updated, err := s.voterRepo.UpdateNameClass(ctx, tx, m.voter.ID, m.row.FullName, m.row.ClassName, m.attributes)
+		if err != nil {
+			return nil, err
+		}
+		if updated == 0 {
+			// Voted after the roster was read.
+			result.SkippedVotedCount++
+			continue
+		}
+		result.UpdatedCount++
+	}
+	processed += len(plan.matches)
+	progress.report(processed)
+
+	added, dbRejected, err := s.bulkInsert(ctx, tx, eventID, plan.newRows, processed, progress)
+	if err != nil {
+		return nil, err
+	}
+	result.AddedCount = added
+	result.Rejected = append(result.Rejected, dbRejected...)
+
+	if disableMissing {
+		disabled, err := s.voterRepo.DisableMissing(ctx, tx, eventID, plan.fileNIMs)
+		if err != nil {
+			return nil, err
+		}
+		result.DisabledCount = int(disabled)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if result.Rejected == nil {
+		result.Rejected = []dto.ImportReject{}
+	}
+	sortRejects(result.Rejected)
+
+	meta, _ := json.Marshal(map[string]interface{}{
+		"disable_missing": disableMissing,
+		"added":           result.AddedCount,
+		"updated":         result.UpdatedCount,
+		"unchanged":       result.UnchangedCount,
+		"disabled":        result.DisabledCount,
+		"skipped_voted":   result.SkippedVotedCount,
+		"rejected":        len(result.Rejected),
+	})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "voters.synced", string(meta))
+
+	return result, nil
+}
+
+// PreviewImport reports what Import, or with sync SyncImport, would do with
+// the upload without writing anything: the voters it would add, NIMs already
+// on the roster whose name, class or attributes differ from the file, the rejects, and
+// whether the package limit would be exceeded. A sync preview also counts the
+// voters skipped for having voted and, with disableMissing, those that would
+// be disabled.
+func (s *VoterService) PreviewImport(ctx context.Context, eventID, userID string, upload RosterUpload, sync, disableMissing bool) (*dto.ImportPreview, error) {
+	plan, err := s.planRoster(ctx, eventID, userID, upload)
+	if err != nil {
+		return nil, err
+	}
+	if disableMissing && len(plan.fileNIMs) == 0 {
+		return nil, ErrEmptyRoster
+	}
+
+	preview := &dto.ImportPreview{
+		Mode:         "insert",
+		NewVoters:    make([]dto.ImportPreviewRow, 0, len(plan.newRows)),
+		Changed:      []dto.ImportPreviewChange{},
+		Rejected:     plan.rejected,
+		CurrentCount: plan.currentCount,
+		MaxVoters:    plan.event.MaxVoters,
+		ExceedsLimit: plan.exceedsLimit(),
+	}
+	for _, row := range plan.newRows {
+		preview.NewVoters = append(preview.NewVoters, dto.ImportPreviewRow{
+			Row:       row.Row,
+			FullName:  row.FullName,
+			NIMRaw:    row.NIMRaw,
+			ClassName: row.ClassName,
+		})
+	}
+	if sync {
+		preview.Mode = "sync"
+		if disableMissing {
+			if preview.DisabledCount, err = s.voterRepo.CountMissing(ctx, eventID, plan.fileNIMs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, m := range plan.matches {
+		if !sync {
+			preview.Rejected = append(preview.Rejected, dto.ImportReject{Row: m.row.Row, Reason: "duplicate nim in event"})
+		} else if m.voter.HasVoted {
+			preview.SkippedVotedCount++
+			continue
+		}
+		if m.unchanged() {
+			preview.UnchangedCount++
+			continue
+		}
+		preview.Changed = append(preview.Changed, dto.ImportPreviewChange{
+			Row:           m.row.Row,
+			VoterID:       m.voter.ID,
+			NIMRaw:        m.voter.NIMRaw,
+			FullName:      m.voter.FullName,
+			ClassName:     deref(m.voter.ClassName),
+			NewFullName:   m.row.FullName,
+			NewClassName:  m.row.ClassName,
+			Attributes:    m.voter.Attributes,
+			NewAttributes: m.attributes,
+		})
+	}
+	if preview.Rejected == nil {
+		preview.Rejected = []dto.ImportReject{}
+	}
+	sortRejects(preview.Rejected)
+	return preview, nil
+}
+
+// sortRejects orders rejects by source row.
+func sortRejects(rejects []dto.ImportReject) {
+	sort.SliceStable(rejects, func(i, j int) bool { return rejects[i].Row < rejects[j].Row })
+}
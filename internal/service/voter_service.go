@@ -2,9 +2,15 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
-	"io"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/amard/pemilo-golang/internal/config"
 	"github.com/amard/pemilo-golang/internal/dto"
 	"github.com/amard/pemilo-golang/internal/model"
 	"github.com/amard/pemilo-golang/internal/repository"
@@ -12,31 +18,55 @@ import (
 )
 
 var (
-	ErrMaxVotersReached = errors.New("maximum number of voters reached for this package")
+	ErrMaxVotersReached   = errors.New("maximum number of voters reached for this package")
+	ErrVoterNotFound      = errors.New("voter not found")
+	ErrTokenNotActive     = errors.New("voter has no active token")
+	ErrTokenNotReissuable = errors.New("voter cannot be issued a new token")
+	ErrDOBRequired        = errors.New("dob is required for this event")
+	ErrPhoneRequired      = errors.New("phone is required for this event")
+	ErrInvalidNIM         = errors.New("invalid nim")
+	ErrNIMFormat          = errors.New("nim does not match the event's nim format")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrInvalidPhone       = errors.New("invalid phone")
+	ErrInvalidDOB         = errors.New("invalid dob (use YYYY-MM-DD or DD-MM-YYYY)")
+	ErrFullNameRequired   = errors.New("full_name is required")
+	ErrInvalidSort        = errors.New("invalid sort (use created_at, full_name, nim, class_name or voted_at)")
+	ErrInvalidCursor      = errors.New("invalid or expired cursor")
+	ErrDuplicateNIM       = errors.New("a voter with this nim already exists in the event")
+	ErrVoterHasVoted      = errors.New("voter has already voted")
+	ErrEmptyRoster        = errors.New("file has no voters; refusing to disable the whole roster")
+	ErrUnknownAttribute   = errors.New("unknown voter attribute for this event")
+	ErrAttributeTooLong   = errors.New("voter attribute value is too long")
 )
 
 type VoterService struct {
+	db             *sql.DB
 	voterRepo      *repository.VoterRepo
 	voterTokenRepo *repository.VoterTokenRepo
 	eventRepo      *repository.EventRepo
 	auditLogRepo   *repository.AuditLogRepo
+	cfg            *config.Config
 }
 
 func NewVoterService(
+	db *sql.DB,
 	voterRepo *repository.VoterRepo,
 	voterTokenRepo *repository.VoterTokenRepo,
 	eventRepo *repository.EventRepo,
 	auditLogRepo *repository.AuditLogRepo,
+	cfg *config.Config,
 ) *VoterService {
 	return &VoterService{
+		db:             db,
 		voterRepo:      voterRepo,
 		voterTokenRepo: voterTokenRepo,
 		eventRepo:      eventRepo,
 		auditLogRepo:   auditLogRepo,
+		cfg:            cfg,
 	}
 }
 
-func (s *VoterService) ImportCSV(ctx context.Context, eventID, userID string, file io.Reader) (*dto.ImportResult, error) {
+func (s *VoterService) List(ctx context.Context, eventID, userID string, params dto.VoterListParams) (*dto.VoterListResponse, error) {
 	event, err := s.eventRepo.GetByID(ctx, eventID)
 	if err != nil {
 		return nil, ErrEventNotFound
@@ -44,111 +74,267 @@ func (s *VoterService) ImportCSV(ctx context.Context, eventID, userID string, fi
 	if event.OwnerUserID != userID {
 		return nil, ErrEventForbidden
 	}
-	if event.Status == model.EventStatusLocked {
-		return nil, ErrEventLocked
+
+	if params.Sort == "" {
+		params.Sort = "created_at"
+	}
+	if !repository.IsVoterSortField(params.Sort) {
+		return nil, ErrInvalidSort
+	}
+	if params.Cursor != "" {
+		after, err := decodeVoterCursor(params.Cursor, params.Sort, params.Desc)
+		if err != nil {
+			return nil, err
+		}
+		params.After = after
 	}
 
-	// Check current count
-	currentCount, err := s.voterRepo.CountByEvent(ctx, eventID)
+	voters, total, hasMore, err := s.voterRepo.List(ctx, eventID, params)
 	if err != nil {
 		return nil, err
 	}
+	var next *string
+	if hasMore {
+		last := &voters[len(voters)-1]
+		c := encodeVoterCursor(params.Sort, params.Desc, repository.VoterSortValue(last, params.Sort), last.ID)
+		next = &c
+	}
 
-	parsed, err := util.ParseVotersCSV(file)
+	tokenMap, err := s.voterTokenRepo.GetTokenMapByEventID(ctx, eventID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check limit
-	if currentCount+len(parsed.Rows) > event.MaxVoters {
-		return nil, ErrMaxVotersReached
+	voterDTOs := make([]dto.VoterDTO, len(voters))
+	for i, v := range voters {
+		var token *string
+		if t, ok := tokenMap[v.ID]; ok {
+			token = &t
+		}
+		voterDTOs[i] = voterDTO(&v, token)
 	}
 
-	// Convert to bulk insert format
-	bulkRows := make([]struct {
-		FullName      string
-		NIMRaw        string
-		NIMNormalized string
-		ClassName     string
-	}, len(parsed.Rows))
+	return &dto.VoterListResponse{
+		Voters:     voterDTOs,
+		Total:      total,
+		NextCursor: next,
+		Page:       params.Page,
+		PerPage:    params.PerPage,
+	}, nil
+}
+
+// voterCursor is the opaque list cursor. It records the ordering it was
+// issued for so it cannot be replayed against a different sort.
+type voterCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
 
-	for i, row := range parsed.Rows {
-		bulkRows[i] = struct {
-			FullName      string
-			NIMRaw        string
-			NIMNormalized string
-			ClassName     string
-		}{
-			FullName:      row.FullName,
-			NIMRaw:        row.NIMRaw,
-			NIMNormalized: row.NIMNormalized,
-			ClassName:     row.ClassName,
+func encodeVoterCursor(sort string, desc bool, value, id string) string {
+	raw, _ := json.Marshal(voterCursor{Sort: sort, Desc: desc, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeVoterCursor(s, sort string, desc bool) (*dto.VoterCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c voterCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || c.Desc != desc || !util.ValidUUID(c.ID) {
+		return nil, ErrInvalidCursor
+	}
+	if sort == "created_at" || sort == "voted_at" {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil && !(sort == "voted_at" && c.Value == "-infinity") {
+			return nil, ErrInvalidCursor
 		}
 	}
+	return &dto.VoterCursor{Value: c.Value, ID: c.ID}, nil
+}
 
-	imported, dbRejected, err := s.voterRepo.BulkInsert(ctx, eventID, bulkRows)
+// CreateVoter adds a single voter within the package's voter limit.
+func (s *VoterService) CreateVoter(ctx context.Context, eventID, userID string, req dto.CreateVoterRequest) (*dto.VoterDTO, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
 	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return nil, ErrEventLocked
+	}
+
+	row, dob, err := voterInputRow(nimPolicy(event), req.FullName, req.NIM, req.ClassName, req.Email, req.Phone, req.DOB)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAttributeKeys(event, req.Attributes); err != nil {
+		return nil, err
+	}
+	if row.Attributes, err = eventAttributes(event, req.Attributes); err != nil {
+		return nil, err
+	}
+	if err := applySecondFactor(s.cfg.SecondFactorKey, event, &row, dob); err != nil {
 		return nil, err
 	}
 
-	// Merge CSV-level rejections with DB-level rejections
-	allRejected := make([]dto.ImportReject, 0, len(parsed.Rejected)+len(dbRejected))
-	for _, r := range parsed.Rejected {
-		allRejected = append(allRejected, dto.ImportReject{Row: r.Row, Reason: r.Reason})
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
-	allRejected = append(allRejected, dbRejected...)
+	defer tx.Rollback()
 
-	s.auditLogRepo.Create(ctx, eventID, &userID, "voters.imported", `{}`)
+	// The event row lock serializes concurrent additions against MaxVoters.
+	if event, err = s.eventRepo.LockForUpdate(ctx, tx, eventID); err != nil {
+		return nil, err
+	}
+	count, err := s.voterRepo.CountByEventInTx(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= event.MaxVoters {
+		return nil, ErrMaxVotersReached
+	}
 
-	return &dto.ImportResult{
-		ImportedCount: imported,
-		Rejected:      allRejected,
-	}, nil
+	voter, err := s.voterRepo.CreateInTx(ctx, tx, eventID, row)
+	if err == sql.ErrNoRows {
+		return nil, ErrDuplicateNIM
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"voter_id": voter.ID, "after": voterAuditView(voter)})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "voter.created", string(meta))
+
+	out := voterDTO(voter, nil)
+	return &out, nil
 }
 
-func (s *VoterService) List(ctx context.Context, eventID, userID string, params dto.VoterListParams) (*dto.VoterListResponse, error) {
-	event, err := s.eventRepo.GetByID(ctx, eventID)
+// UpdateVoter edits a voter's roster fields.
+func (s *VoterService) UpdateVoter(ctx context.Context, eventID, voterID, userID string, req dto.UpdateVoterRequest) (*dto.VoterDTO, error) {
+	event, voter, err := s.getEditableVoter(ctx, eventID, voterID, userID)
 	if err != nil {
-		return nil, ErrEventNotFound
+		return nil, err
 	}
-	if event.OwnerUserID != userID {
-		return nil, ErrEventForbidden
+
+	fullName, nim := voter.FullName, voter.NIMRaw
+	className, email, phone := deref(voter.ClassName), deref(voter.Email), deref(voter.Phone)
+	if req.FullName != nil {
+		fullName = *req.FullName
+	}
+	if req.NIM != nil {
+		nim = *req.NIM
+	}
+	if req.ClassName != nil {
+		className = *req.ClassName
+	}
+	if req.Email != nil {
+		email = *req.Email
+	}
+	if req.Phone != nil {
+		phone = *req.Phone
 	}
 
-	voters, total, err := s.voterRepo.List(ctx, eventID, params)
+	row, dob, err := voterInputRow(nimPolicy(event), fullName, nim, className, email, phone, deref(req.DOB))
 	if err != nil {
 		return nil, err
 	}
+	row.Attributes = voter.Attributes
+	if req.Attributes != nil {
+		if err := checkAttributeKeys(event, req.Attributes); err != nil {
+			return nil, err
+		}
+		if row.Attributes, err = eventAttributes(event, req.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	// A date of birth already on file satisfies the second factor.
+	if err := applySecondFactor(s.cfg.SecondFactorKey, event, &row, dob); err != nil && !(err == ErrDOBRequired && voter.DOBHash != nil) {
+		return nil, err
+	}
 
-	tokenMap, err := s.voterTokenRepo.GetTokenMapByEventID(ctx, eventID)
+	updated, err := s.voterRepo.UpdateDetails(ctx, voterID, row)
 	if err != nil {
+		if strings.Contains(err.Error(), "uq_voters_event_nim") {
+			return nil, ErrDuplicateNIM
+		}
 		return nil, err
 	}
 
-	voterDTOs := make([]dto.VoterDTO, len(voters))
-	for i, v := range voters {
-		var token *string
-		if t, ok := tokenMap[v.ID]; ok {
-			token = &t
+	meta, _ := json.Marshal(map[string]interface{}{
+		"voter_id": voterID,
+		"before":   voterAuditView(voter),
+		"after":    voterAuditView(updated),
+	})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "voter.updated", string(meta))
+
+	out := voterDTO(updated, nil)
+	return &out, nil
+}
+
+// SetVoterStatus enables or disables a voter. A disabled voter keeps their
+// token but can neither open a session nor submit a ballot.
+func (s *VoterService) SetVoterStatus(ctx context.Context, eventID, voterID, userID string, status model.VoterStatus) (*dto.VoterDTO, error) {
+	_, voter, err := s.getEditableVoter(ctx, eventID, voterID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := voter.Status
+	if before != status {
+		if err := s.voterRepo.SetStatus(ctx, voterID, status); err != nil {
+			return nil, err
 		}
-		voterDTOs[i] = dto.VoterDTO{
-			ID:        v.ID,
-			FullName:  v.FullName,
-			NIMRaw:    v.NIMRaw,
-			ClassName: v.ClassName,
-			HasVoted:  v.HasVoted,
-			VotedAt:   v.VotedAt,
-			Status:    string(v.Status),
-			Token:     token,
+		voter.Status = status
+
+		action := "voter.enabled"
+		if status == model.VoterStatusDisabled {
+			action = "voter.disabled"
 		}
+		meta, _ := json.Marshal(map[string]interface{}{
+			"voter_id": voterID,
+			"before":   map[string]interface{}{"status": before},
+			"after":    map[string]interface{}{"status": status},
+		})
+		s.auditLogRepo.Create(ctx, eventID, &userID, action, string(meta))
 	}
 
-	return &dto.VoterListResponse{
-		Voters:  voterDTOs,
-		Total:   total,
-		Page:    params.Page,
-		PerPage: params.PerPage,
-	}, nil
+	out := voterDTO(voter, nil)
+	return &out, nil
+}
+
+// DeleteVoter removes a voter together with their tokens and deliveries.
+// Voters who have voted are kept so turnout stays consistent with the ballots.
+func (s *VoterService) DeleteVoter(ctx context.Context, eventID, voterID, userID string) error {
+	_, voter, err := s.getEditableVoter(ctx, eventID, voterID, userID)
+	if err != nil {
+		return err
+	}
+	if voter.HasVoted {
+		return ErrVoterHasVoted
+	}
+
+	deleted, err := s.voterRepo.Delete(ctx, voterID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrVoterHasVoted
+	}
+
+	meta, _ := json.Marshal(map[string]interface{}{"voter_id": voterID, "before": voterAuditView(voter)})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "voter.deleted", string(meta))
+	return nil
 }
 
 func (s *VoterService) GenerateTokens(ctx context.Context, eventID, userID string) (int, error) {
@@ -168,9 +354,10 @@ func (s *VoterService) GenerateTokens(ctx context.Context, eventID, userID strin
 		return 0, err
 	}
 
+	policy := tokenPolicy(event)
 	count := 0
 	for _, voter := range voters {
-		token, err := util.GenerateToken()
+		token, err := util.GenerateToken(policy)
 		if err != nil {
 			return count, err
 		}
@@ -178,7 +365,7 @@ func (s *VoterService) GenerateTokens(ctx context.Context, eventID, userID strin
 		_, err = s.voterTokenRepo.Create(ctx, eventID, voter.ID, token)
 		if err != nil {
 			// Collision — retry once
-			token, err = util.GenerateToken()
+			token, err = util.GenerateToken(policy)
 			if err != nil {
 				return count, err
 			}
@@ -194,7 +381,298 @@ func (s *VoterService) GenerateTokens(ctx context.Context, eventID, userID strin
 	return count, nil
 }
 
-func (s *VoterService) ExportTokens(ctx context.Context, eventID, userID string) ([]repository.TokenExportRow, error) {
+// RevokeToken revokes the voter's ACTIVE token. The token row is kept with the
+// reason so its history stays visible.
+func (s *VoterService) RevokeToken(ctx context.Context, eventID, voterID, userID, reason string) error {
+	if _, _, err := s.getEditableVoter(ctx, eventID, voterID, userID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	vt, err := s.voterTokenRepo.LockLiveByVoter(ctx, tx, voterID)
+	if err == sql.ErrNoRows {
+		return ErrTokenNotActive
+	}
+	if err != nil {
+		return err
+	}
+	if vt.Status != model.TokenStatusActive {
+		return ErrTokenNotActive
+	}
+
+	if _, err := s.voterTokenRepo.Revoke(ctx, tx, vt.ID, reason); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	meta, _ := json.Marshal(map[string]string{"voter_id": voterID, "token_id": vt.ID, "reason": reason})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "token.revoked", string(meta))
+	return nil
+}
+
+// ReissueToken issues a replacement token. A still-ACTIVE token is revoked
+// first with the same reason; after that the voter must qualify under the
+// GetVotersWithoutToken rules (eligible, not voted, no live token).
+func (s *VoterService) ReissueToken(ctx context.Context, eventID, voterID, userID, reason string) (*model.VoterToken, error) {
+	event, _, err := s.getEditableVoter(ctx, eventID, voterID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var revokedTokenID string
+	live, err := s.voterTokenRepo.LockLiveByVoter(ctx, tx, voterID)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, err
+	case live.Status == model.TokenStatusActive:
+		if _, err := s.voterTokenRepo.Revoke(ctx, tx, live.ID, reason); err != nil {
+			return nil, err
+		}
+		revokedTokenID = live.ID
+	}
+
+	ok, err := s.voterRepo.IsWithoutToken(ctx, tx, voterID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTokenNotReissuable
+	}
+
+	token, err := util.GenerateToken(tokenPolicy(event))
+	if err != nil {
+		return nil, err
+	}
+	vt, err := s.voterTokenRepo.CreateInTx(ctx, tx, eventID, voterID, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.voterTokenRepo.ResetDeliveries(ctx, tx, voterID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	meta, _ := json.Marshal(map[string]string{
+		"voter_id":         voterID,
+		"token_id":         vt.ID,
+		"revoked_token_id": revokedTokenID,
+		"reason":           reason,
+	})
+	s.auditLogRepo.Create(ctx, eventID, &userID, "token.reissued", string(meta))
+	return vt, nil
+}
+
+// TokenHistory lists every token issued to a voter, including revoked ones.
+func (s *VoterService) TokenHistory(ctx context.Context, eventID, voterID, userID string) ([]model.VoterToken, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, ErrEventForbidden
+	}
+
+	voter, err := s.voterRepo.GetByID(ctx, voterID)
+	if err != nil || voter.EventID != eventID {
+		return nil, ErrVoterNotFound
+	}
+
+	tokens, err := s.voterTokenRepo.ListByVoter(ctx, voterID)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []model.VoterToken{}
+	}
+	return tokens, nil
+}
+
+func voterDTO(v *model.Voter, token *string) dto.VoterDTO {
+	return dto.VoterDTO{
+		ID:         v.ID,
+		FullName:   v.FullName,
+		NIMRaw:     v.NIMRaw,
+		ClassName:  v.ClassName,
+		Email:      v.Email,
+		Phone:      v.Phone,
+		HasVoted:   v.HasVoted,
+		VotedAt:    v.VotedAt,
+		Status:     string(v.Status),
+		Token:      token,
+		Attributes: v.Attributes,
+	}
+}
+
+// voterAuditView is the voter snapshot written to audit entries. The date of
+// birth hash is reduced to whether one is on file.
+func voterAuditView(v *model.Voter) map[string]interface{} {
+	return map[string]interface{}{
+		"full_name":  v.FullName,
+		"nim":        v.NIMRaw,
+		"class_name": v.ClassName,
+		"email":      v.Email,
+		"phone":      v.Phone,
+		"dob_set":    v.DOBHash != nil,
+		"attributes": v.Attributes,
+		"status":     v.Status,
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// voterInputRow validates a single voter entered by hand with the same rules
+// as a roster CSV row, normalizing the NIM with the event's policy. The
+// normalized date of birth is returned separately so only its hash is stored.
+func voterInputRow(nimPolicy util.NIMPolicy, fullName, nim, className, email, phone, dob string) (row repository.VoterInsertRow, normDOB string, err error) {
+	row = repository.VoterInsertRow{
+		FullName:  strings.TrimSpace(fullName),
+		NIMRaw:    strings.TrimSpace(nim),
+		ClassName: strings.TrimSpace(className),
+		Email:     strings.TrimSpace(email),
+	}
+	row.NIMNormalized = nimPolicy.Normalize(row.NIMRaw)
+
+	if row.FullName == "" {
+		return row, "", ErrFullNameRequired
+	}
+	if row.NIMNormalized == "" || len(row.NIMNormalized) > 50 {
+		return row, "", ErrInvalidNIM
+	}
+	if !nimPolicy.Matches(row.NIMNormalized) {
+		return row, "", ErrNIMFormat
+	}
+	if row.Email != "" && !util.ValidateEmail(row.Email) {
+		return row, "", ErrInvalidEmail
+	}
+	if raw := strings.TrimSpace(phone); raw != "" {
+		row.Phone = util.NormalizePhone(raw)
+		if !util.ValidatePhone(row.Phone) {
+			return row, "", ErrInvalidPhone
+		}
+	}
+	if raw := strings.TrimSpace(dob); raw != "" {
+		var ok bool
+		if normDOB, ok = util.NormalizeDOB(raw); !ok {
+			return row, "", ErrInvalidDOB
+		}
+	}
+	return row, normDOB, nil
+}
+
+// applySecondFactor checks that a roster row carries the attribute the event's
+// second factor needs and stores the date of birth and phone digits as keyed
+// hashes.
+func applySecondFactor(key string, event *model.Event, row *repository.VoterInsertRow, dob string) error {
+	if dob != "" {
+		row.DOBHash = util.HashSecondFactor(key, event.ID, dob)
+	}
+	row.PhoneLast4Hash = phoneLast4Hash(key, event.ID, row.Phone)
+	if event.SecondFactor == nil {
+		return nil
+	}
+	switch *event.SecondFactor {
+	case model.SecondFactorDOB:
+		if dob == "" {
+			return ErrDOBRequired
+		}
+	case model.SecondFactorPhoneLast4:
+		if row.Phone == "" {
+			return ErrPhoneRequired
+		}
+	}
+	return nil
+}
+
+// phoneLast4Hash is the keyed hash of a phone number's last four digits, or ""
+// without a usable phone.
+func phoneLast4Hash(key, eventID, phone string) string {
+	last4 := util.PhoneLast4(phone)
+	if last4 == "" {
+		return ""
+	}
+	return util.HashSecondFactor(key, eventID, last4)
+}
+
+// hashMissingPhones fills in phone_last4_hash for voters that have a phone
+// without one: voters copied from another event, whose hashes were keyed to
+// it, and voters stored before the hash was kept. An empty eventID covers
+// every event.
+func hashMissingPhones(ctx context.Context, tx *sql.Tx, voterRepo *repository.VoterRepo, key, eventID string) (int, error) {
+	voters, err := voterRepo.PhonesWithoutLast4Hash(ctx, tx, eventID)
+	if err != nil {
+		return 0, err
+	}
+	var ids, hashes []string
+	for _, v := range voters {
+		if hash := phoneLast4Hash(key, v.EventID, deref(v.Phone)); hash != "" {
+			ids = append(ids, v.ID)
+			hashes = append(hashes, hash)
+		}
+	}
+	return len(ids), voterRepo.SetPhoneLast4Hashes(ctx, tx, ids, hashes)
+}
+
+// HashMissingPhones fills in the phone hashes of every event's voters that
+// lack one. It runs at startup so rosters stored before the hash existed keep
+// working with the PHONE_LAST4 second factor.
+func (s *VoterService) HashMissingPhones(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := hashMissingPhones(ctx, tx, s.voterRepo, s.cfg.SecondFactorKey, "")
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// getEditableVoter loads a voter of an event the user owns and that is not locked.
+func (s *VoterService) getEditableVoter(ctx context.Context, eventID, voterID, userID string) (*model.Event, *model.Voter, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, nil, ErrEventForbidden
+	}
+	if event.Status == model.EventStatusLocked {
+		return nil, nil, ErrEventLocked
+	}
+
+	voter, err := s.voterRepo.GetByID(ctx, voterID)
+	if err != nil || voter.EventID != eventID {
+		return nil, nil, ErrVoterNotFound
+	}
+	return event, voter, nil
+}
+
+// ExportTokens returns every issued token. With includeLinks, ACTIVE tokens
+// also get their signed per-voter voting link.
+func (s *VoterService) ExportTokens(ctx context.Context, eventID, userID string, includeLinks bool) ([]repository.TokenExportRow, error) {
 	event, err := s.eventRepo.GetByID(ctx, eventID)
 	if err != nil {
 		return nil, ErrEventNotFound
@@ -203,7 +681,78 @@ func (s *VoterService) ExportTokens(ctx context.Context, eventID, userID string)
 		return nil, ErrEventForbidden
 	}
 
-	return s.voterTokenRepo.GetTokensForExport(ctx, eventID)
+	rows, err := s.voterTokenRepo.GetTokensForExport(ctx, eventID)
+	if err != nil || !includeLinks {
+		return rows, err
+	}
+
+	for i := range rows {
+		if rows[i].Status != model.TokenStatusActive {
+			continue
+		}
+		if rows[i].VotingLink, err = s.votingLink(eventID, rows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// TokenCards returns printable cards for every ACTIVE token, grouped by
+// class_name (voters without a class last) and by name within each class.
+func (s *VoterService) TokenCards(ctx context.Context, eventID, userID string) (*model.Event, []util.TokenCard, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, nil, ErrEventNotFound
+	}
+	if event.OwnerUserID != userID {
+		return nil, nil, ErrEventForbidden
+	}
+
+	rows, err := s.voterTokenRepo.GetTokensForExport(ctx, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cards := make([]util.TokenCard, 0, len(rows))
+	for _, row := range rows {
+		if row.Status != model.TokenStatusActive {
+			continue
+		}
+		link, err := s.votingLink(eventID, row)
+		if err != nil {
+			return nil, nil, err
+		}
+		className := ""
+		if row.ClassName != nil {
+			className = *row.ClassName
+		}
+		cards = append(cards, util.TokenCard{
+			FullName:  row.FullName,
+			NIM:       row.NIMRaw,
+			ClassName: className,
+			Token:     row.Token,
+			QRContent: link,
+		})
+	}
+
+	// Rows arrive sorted by full_name; a stable sort keeps that within each class.
+	sort.SliceStable(cards, func(i, j int) bool {
+		ci, cj := cards[i].ClassName, cards[j].ClassName
+		if (ci == "") != (cj == "") {
+			return cj == ""
+		}
+		return ci < cj
+	})
+
+	return event, cards, nil
+}
+
+func (s *VoterService) votingLink(eventID string, row repository.TokenExportRow) (string, error) {
+	credential, err := util.SignVoteLink(s.cfg.VoteLinkSecret, eventID, row.TokenID, row.Token)
+	if err != nil {
+		return "", err
+	}
+	return util.VoteLinkURL(s.cfg.VoteBaseURL, eventID, credential), nil
 }
 
 func (s *VoterService) ExportTurnout(ctx context.Context, eventID, userID string) ([]model.Voter, error) {
@@ -217,3 +766,44 @@ func (s *VoterService) ExportTurnout(ctx context.Context, eventID, userID string
 
 	return s.voterRepo.GetAllVotersForExport(ctx, eventID)
 }
+
+// eventAttributes keeps the values of attributes the event defines and drops
+// the rest, e.g. stray spreadsheet columns. Values are trimmed; empty ones are
+// left out.
+func eventAttributes(event *model.Event, attrs map[string]string) (map[string]string, error) {
+	var out map[string]string
+	for _, key := range event.VoterAttributes {
+		v := strings.TrimSpace(attrs[key])
+		if v == "" {
+			continue
+		}
+		if len(v) > util.MaxAttributeValueLen {
+			return nil, ErrAttributeTooLong
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// checkAttributeKeys rejects attributes the event does not define, for input
+// that names them explicitly rather than via file columns.
+func checkAttributeKeys(event *model.Event, attrs map[string]string) error {
+	for key := range attrs {
+		if !hasAttribute(event, key) {
+			return ErrUnknownAttribute
+		}
+	}
+	return nil
+}
+
+func hasAttribute(event *model.Event, key string) bool {
+	for _, k := range event.VoterAttributes {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxAttributeValueLen caps a custom voter attribute value.
+const MaxAttributeValueLen = 100
+
+var attributeKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// NormalizeAttributeKey turns a column header such as "Cohort Year" into the
+// attribute key "cohort_year".
+func NormalizeAttributeKey(header string) string {
+	key := strings.ToLower(strings.TrimSpace(header))
+	return whitespaceRe.ReplaceAllString(key, "_")
+}
+
+// ValidAttributeKey reports whether key can name a custom voter attribute:
+// lower-case snake case, at most 32 characters, and not a roster column or
+// one of its header aliases.
+func ValidAttributeKey(key string) bool {
+	_, reserved := rosterHeaders[key]
+	return attributeKeyRe.MatchString(key) && !reserved
+}
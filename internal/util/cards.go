@@ -0,0 +1,137 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// TokenCard is one printable voter slip.
+type TokenCard struct {
+	FullName  string
+	NIM       string
+	ClassName string
+	Token     string
+	QRContent string
+}
+
+// CardLayout is a grid of cards on an A4 portrait page.
+type CardLayout struct {
+	Cols int
+	Rows int
+}
+
+// CardLayouts are the supported cards-per-page options.
+var CardLayouts = map[int]CardLayout{
+	4:  {Cols: 2, Rows: 2},
+	8:  {Cols: 2, Rows: 4},
+	10: {Cols: 2, Rows: 5},
+	12: {Cols: 3, Rows: 4},
+}
+
+const (
+	cardPageMargin = 10.0 // mm
+	cardPadding    = 3.0  // mm
+)
+
+// RenderTokenCardsPDF writes an A4 PDF with one card per voter. Cards must
+// already be ordered; a new page is started whenever ClassName changes so each
+// class's slips can be cut and handed out together.
+func RenderTokenCardsPDF(w io.Writer, eventTitle string, cards []TokenCard, layout CardLayout) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(cardPageMargin, cardPageMargin, cardPageMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pageW, pageH := pdf.GetPageSize()
+	cardW := (pageW - 2*cardPageMargin) / float64(layout.Cols)
+	cardH := (pageH - 2*cardPageMargin) / float64(layout.Rows)
+	perPage := layout.Cols * layout.Rows
+
+	slot := perPage // forces a page on the first card
+	prevClass := ""
+	for i, card := range cards {
+		if slot == perPage || (i > 0 && card.ClassName != prevClass) {
+			pdf.AddPage()
+			slot = 0
+		}
+		prevClass = card.ClassName
+
+		x := cardPageMargin + float64(slot%layout.Cols)*cardW
+		y := cardPageMargin + float64(slot/layout.Cols)*cardH
+		if err := drawTokenCard(pdf, tr, fmt.Sprintf("qr%d", i), eventTitle, card, x, y, cardW, cardH); err != nil {
+			return err
+		}
+		slot++
+	}
+
+	if len(cards) == 0 {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.CellFormat(0, 10, tr("No active tokens to print."), "", 1, "C", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+func drawTokenCard(pdf *fpdf.Fpdf, tr func(string) string, imgName, eventTitle string, card TokenCard, x, y, w, h float64) error {
+	// Dashed cut guide
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetDashPattern([]float64{1.5, 1.5}, 0)
+	pdf.Rect(x, y, w, h, "D")
+	pdf.SetDashPattern([]float64{}, 0)
+
+	qrSize := h - 2*cardPadding - 6
+	if maxQR := w * 0.42; qrSize > maxQR {
+		qrSize = maxQR
+	}
+	textW := w - qrSize - 3*cardPadding
+
+	png, err := qrcode.Encode(card.QRContent, qrcode.Medium, 512)
+	if err != nil {
+		return fmt.Errorf("encode qr: %w", err)
+	}
+	pdf.RegisterImageOptionsReader(imgName, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	pdf.ImageOptions(imgName, x+w-cardPadding-qrSize, y+cardPadding+4, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	cx := x + cardPadding
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(cx, y+cardPadding)
+	pdf.CellFormat(w-2*cardPadding, 4, tr(truncateToWidth(pdf, eventTitle, w-2*cardPadding)), "", 0, "L", false, 0, "")
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetXY(cx, y+cardPadding+6)
+	pdf.CellFormat(textW, 5, tr(truncateToWidth(pdf, card.FullName, textW)), "", 2, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(textW, 4.5, tr("NIM: "+card.NIM), "", 2, "L", false, 0, "")
+	if card.ClassName != "" {
+		pdf.CellFormat(textW, 4.5, tr(truncateToWidth(pdf, "Kelas: "+card.ClassName, textW)), "", 2, "L", false, 0, "")
+	}
+
+	pdf.Ln(2)
+	pdf.SetX(cx)
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.CellFormat(textW, 3.5, "TOKEN", "", 2, "L", false, 0, "")
+	pdf.SetFont("Courier", "B", 16)
+	pdf.CellFormat(textW, 7, card.Token, "", 2, "L", false, 0, "")
+
+	return nil
+}
+
+// truncateToWidth shortens s with an ellipsis so it fits in width at the current font.
+func truncateToWidth(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
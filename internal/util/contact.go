@@ -0,0 +1,42 @@
+package util
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var phoneRegex = regexp.MustCompile(`^\+[0-9]{8,15}$`)
+
+// ValidateEmail reports whether s is a bare email address (no display name).
+func ValidateEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// NormalizePhone strips formatting characters and converts local Indonesian
+// numbers (08xx) to E.164 (+628xx) so gateways receive a consistent format.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if r >= '0' && r <= '9' || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	p := b.String()
+
+	switch {
+	case strings.HasPrefix(p, "+"):
+		return p
+	case strings.HasPrefix(p, "0"):
+		return "+62" + p[1:]
+	case strings.HasPrefix(p, "62"):
+		return "+" + p
+	}
+	return p
+}
+
+// ValidatePhone checks that a normalized phone number is in E.164 form.
+func ValidatePhone(phone string) bool {
+	return phoneRegex.MatchString(phone)
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -8,10 +9,17 @@ import (
 )
 
 type VoterCSVRow struct {
+	Row           int // 1-based line in the file, for error reporting
 	FullName      string
 	NIMRaw        string
 	NIMNormalized string
 	ClassName     string
+	Email         string
+	Phone         string
+	DOB           string // YYYY-MM-DD, see NormalizeDOB
+	// Attributes holds the non-empty values of columns that are not roster
+	// columns, keyed by NormalizeAttributeKey of their header.
+	Attributes map[string]string
 }
 
 type CSVParseResult struct {
@@ -22,12 +30,24 @@ type CSVParseResult struct {
 type CSVReject struct {
 	Row    int
 	Reason string
+	NIM    string // normalized, when the row had a usable one
 }
 
-// ParseVotersCSV parses a voters CSV file (full_name,nim,class_name).
-// Returns valid rows and rejected rows with reasons.
-func ParseVotersCSV(r io.Reader) (*CSVParseResult, error) {
-	reader := csv.NewReader(r)
+// ParseVotersCSV parses a voters CSV file (full_name,nim,class_name,email,phone,dob).
+// Returns valid rows and rejected rows with reasons. The file may start with a
+// BOM, be UTF-16 or Windows-1252 encoded, and use ',', ';' or tab as delimiter.
+// NIMs are normalized and checked with the event's policy.
+func ParseVotersCSV(r io.Reader, nimPolicy NIMPolicy) (*CSVParseResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if data, err = decodeCSVText(data); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data)
 	reader.TrimLeadingSpace = true
 
 	// Read header
@@ -36,24 +56,88 @@ func ParseVotersCSV(r io.Reader) (*CSVParseResult, error) {
 		return nil, fmt.Errorf("failed to read CSV header: %w", err)
 	}
 
+	cols, ok := findRosterColumns(header)
+	if !ok {
+		return nil, fmt.Errorf("CSV must have 'full_name' and 'nim' columns")
+	}
+	return parseRoster(cols, 1, nimPolicy, reader.Read)
+}
+
+// rosterColumns maps the recognised roster columns to their record index;
+// optional columns that are absent are -1.
+type rosterColumns struct {
+	fullName, nim, className, email, phone, dob int
+	// extra maps the remaining named columns to their record index, keyed
+	// by attribute key.
+	extra map[string]int
+}
+
+// rosterHeaders maps every accepted roster header, after
+// NormalizeAttributeKey, to its column: the canonical names plus common
+// Indonesian and English aliases.
+var rosterHeaders = map[string]string{
+	"full_name": "full_name", "nama": "full_name", "nama_lengkap": "full_name", "name": "full_name",
+	"nim": "nim", "npm": "nim", "nis": "nim", "nomor_induk": "nim", "no_induk": "nim",
+	"class_name": "class_name", "kelas": "class_name", "class": "class_name",
+	"email": "email", "e-mail": "email", "surel": "email",
+	"phone": "phone", "telepon": "phone", "no_hp": "phone", "hp": "phone", "whatsapp": "phone", "no_wa": "phone",
+	"dob": "dob", "tanggal_lahir": "dob", "tgl_lahir": "dob", "date_of_birth": "dob",
+}
+
+// findRosterColumns reads a header row. ok is false unless both full_name and
+// nim (or one of their aliases) are present. When a column appears more than
+// once, the first wins.
+func findRosterColumns(header []string) (cols rosterColumns, ok bool) {
 	colMap := make(map[string]int)
 	for i, h := range header {
-		colMap[strings.TrimSpace(strings.ToLower(h))] = i
+		name, known := rosterHeaders[NormalizeAttributeKey(h)]
+		if _, seen := colMap[name]; known && !seen {
+			colMap[name] = i
+		}
 	}
 
-	fnIdx, fnOK := colMap["full_name"]
-	nimIdx, nimOK := colMap["nim"]
-	if !fnOK || !nimOK {
-		return nil, fmt.Errorf("CSV must have 'full_name' and 'nim' columns")
+	lookup := func(name string) int {
+		if i, exists := colMap[name]; exists {
+			return i
+		}
+		return -1
+	}
+	cols = rosterColumns{
+		fullName:  lookup("full_name"),
+		nim:       lookup("nim"),
+		className: lookup("class_name"),
+		email:     lookup("email"),
+		phone:     lookup("phone"),
+		dob:       lookup("dob"),
+		extra:     make(map[string]int),
+	}
+	for i, h := range header {
+		key := NormalizeAttributeKey(h)
+		if _, exists := cols.extra[key]; !exists && ValidAttributeKey(key) {
+			cols.extra[key] = i
+		}
+	}
+	return cols, cols.fullName >= 0 && cols.nim >= 0
+}
+
+// parseRoster validates the records returned by next, which reports io.EOF
+// when the roster ends; any other error rejects that one record and an empty
+// record is a skipped line. headerRow is the 1-based line of the header so
+// rejections point at the source line.
+func parseRoster(cols rosterColumns, headerRow int, nimPolicy NIMPolicy, next func() ([]string, error)) (*CSVParseResult, error) {
+	field := func(record []string, idx int) string {
+		if idx < 0 || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
 	}
-	classIdx, classOK := colMap["class_name"]
 
 	result := &CSVParseResult{}
 	seen := make(map[string]int) // nim_normalized -> first row
-	rowNum := 1                  // 1-based (header is row 0)
+	rowNum := headerRow
 
 	for {
-		record, err := reader.Read()
+		record, err := next()
 		if err == io.EOF {
 			break
 		}
@@ -63,42 +147,88 @@ func ParseVotersCSV(r io.Reader) (*CSVParseResult, error) {
 			continue
 		}
 		rowNum++
+		if len(record) == 0 {
+			continue
+		}
 
-		fullName := strings.TrimSpace(record[fnIdx])
+		fullName := field(record, cols.fullName)
 		if fullName == "" {
 			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, Reason: "full_name is required"})
 			continue
 		}
 
-		if nimIdx >= len(record) || strings.TrimSpace(record[nimIdx]) == "" {
+		nimRaw := field(record, cols.nim)
+		if nimRaw == "" {
 			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, Reason: "nim is required"})
 			continue
 		}
+		nimNorm := nimPolicy.Normalize(nimRaw)
 
-		nimRaw := strings.TrimSpace(record[nimIdx])
-		nimNorm := NormalizeNIM(nimRaw)
-
+		if nimNorm == "" {
+			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, Reason: "nim is empty after normalization"})
+			continue
+		}
 		if len(nimNorm) > 50 {
 			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, Reason: "nim length exceeds 50 characters"})
 			continue
 		}
+		if !nimPolicy.Matches(nimNorm) {
+			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, Reason: fmt.Sprintf("nim %q does not match the event's nim format", nimNorm)})
+			continue
+		}
 
 		if firstRow, exists := seen[nimNorm]; exists {
-			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, Reason: fmt.Sprintf("duplicate nim in file (first at row %d)", firstRow)})
+			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, NIM: nimNorm, Reason: fmt.Sprintf("duplicate nim in file (first at row %d)", firstRow)})
 			continue
 		}
 		seen[nimNorm] = rowNum
 
-		var className string
-		if classOK && classIdx < len(record) {
-			className = strings.TrimSpace(record[classIdx])
+		className := field(record, cols.className)
+
+		email := field(record, cols.email)
+		if email != "" && !ValidateEmail(email) {
+			result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, NIM: nimNorm, Reason: "invalid email"})
+			continue
+		}
+
+		var phone string
+		if raw := field(record, cols.phone); raw != "" {
+			phone = NormalizePhone(raw)
+			if !ValidatePhone(phone) {
+				result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, NIM: nimNorm, Reason: "invalid phone"})
+				continue
+			}
+		}
+
+		var dob string
+		if raw := field(record, cols.dob); raw != "" {
+			var ok bool
+			if dob, ok = NormalizeDOB(raw); !ok {
+				result.Rejected = append(result.Rejected, CSVReject{Row: rowNum, NIM: nimNorm, Reason: "invalid dob (use YYYY-MM-DD or DD-MM-YYYY)"})
+				continue
+			}
+		}
+
+		var attrs map[string]string
+		for key, idx := range cols.extra {
+			if v := field(record, idx); v != "" {
+				if attrs == nil {
+					attrs = make(map[string]string)
+				}
+				attrs[key] = v
+			}
 		}
 
 		result.Rows = append(result.Rows, VoterCSVRow{
+			Row:           rowNum,
 			FullName:      fullName,
 			NIMRaw:        nimRaw,
 			NIMNormalized: nimNorm,
 			ClassName:     className,
+			Email:         email,
+			Phone:         phone,
+			DOB:           dob,
+			Attributes:    attrs,
 		})
 	}
 
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func utf16Bytes(t *testing.T, order unicode.Endianness, s string) []byte {
+	t.Helper()
+	out, err := unicode.UTF16(order, unicode.UseBOM).NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestParseVotersCSV(t *testing.T) {
+	type want struct {
+		fullName, nim, className string
+	}
+	tests := []struct {
+		name  string
+		input func(t *testing.T) []byte
+		want  []want
+	}{
+		{
+			name:  "plain comma",
+			input: func(*testing.T) []byte { return []byte("full_name,nim,class_name\nBudi,001,XII-1\n") },
+			want:  []want{{"Budi", "001", "XII-1"}},
+		},
+		{
+			name:  "UTF-8 BOM",
+			input: func(*testing.T) []byte { return append([]byte{0xEF, 0xBB, 0xBF}, "full_name,nim\nBudi,001\n"...) },
+			want:  []want{{"Budi", "001", ""}},
+		},
+		{
+			name: "UTF-16LE BOM",
+			input: func(t *testing.T) []byte {
+				return utf16Bytes(t, unicode.LittleEndian, "full_name\tnim\tclass_name\r\nSiti Nurhaliza\t002\tXI\r\n")
+			},
+			want: []want{{"Siti Nurhaliza", "002", "XI"}},
+		},
+		{
+			name: "UTF-16BE BOM",
+			input: func(t *testing.T) []byte {
+				return utf16Bytes(t, unicode.BigEndian, "full_name,nim\nSiti,002\n")
+			},
+			want: []want{{"Siti", "002", ""}},
+		},
+		{
+			name:  "Windows-1252 fallback",
+			input: func(*testing.T) []byte { return []byte("full_name;nim\nAndr\xe9 Ca\xf1o;003\n") },
+			want:  []want{{"André Caño", "003", ""}},
+		},
+		{
+			name:  "semicolon delimiter",
+			input: func(*testing.T) []byte { return []byte("full_name;nim;class_name\nBudi;001;XII-1\nAni;002;XII-2\n") },
+			want:  []want{{"Budi", "001", "XII-1"}, {"Ani", "002", "XII-2"}},
+		},
+		{
+			name:  "tab delimiter",
+			input: func(*testing.T) []byte { return []byte("full_name\tnim\tclass_name\nBudi\t001\tXII-1\n") },
+			want:  []want{{"Budi", "001", "XII-1"}},
+		},
+		{
+			name:  "comma inside quoted field with semicolon delimiter",
+			input: func(*testing.T) []byte { return []byte("full_name;nim\n\"Santoso, Budi\";001\n") },
+			want:  []want{{"Santoso, Budi", "001", ""}},
+		},
+		{
+			name:  "semicolons inside quoted header and field",
+			input: func(*testing.T) []byte { return []byte("\"full_name\",nim,\"note;a;b\"\n\"Budi; Jr.\",001,x\n") },
+			want:  []want{{"Budi; Jr.", "001", ""}},
+		},
+		{
+			name:  "nama kelas npm aliases",
+			input: func(*testing.T) []byte { return []byte("Nama,NPM,Kelas\nBudi,001,XII-1\n") },
+			want:  []want{{"Budi", "001", "XII-1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseVotersCSV(bytes.NewReader(tt.input(t)), NIMPolicy{})
+			if err != nil {
+				t.Fatalf("ParseVotersCSV: %v", err)
+			}
+			if len(result.Rejected) > 0 {
+				t.Fatalf("unexpected rejects: %+v", result.Rejected)
+			}
+			if len(result.Rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(result.Rows), len(tt.want))
+			}
+			for i, w := range tt.want {
+				got := result.Rows[i]
+				if got.FullName != w.fullName || got.NIMNormalized != w.nim || got.ClassName != w.className {
+					t.Errorf("row %d = {%q %q %q}, want {%q %q %q}", i, got.FullName, got.NIMNormalized, got.ClassName, w.fullName, w.nim, w.className)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVotersCSVMissingColumns(t *testing.T) {
+	if _, err := ParseVotersCSV(bytes.NewReader([]byte("kelas,email\nXII,a@b.c\n")), NIMPolicy{}); err == nil {
+		t.Fatal("expected an error without full_name and nim columns")
+	}
+}
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		input string
+		want  rune
+	}{
+		{"a,b,c\n", ','},
+		{"a;b;c\n", ';'},
+		{"a\tb\tc\n", '\t'},
+		{"\"a,b,c\";d;e\n", ';'},
+		{"\n\na;b\n", ';'},
+		{"a\n", ','},
+	}
+	for _, tt := range tests {
+		if got := sniffDelimiter([]byte(tt.input)); got != tt.want {
+			t.Errorf("sniffDelimiter(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
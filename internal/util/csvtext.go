@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeCSVText turns an uploaded CSV into UTF-8 without a byte order mark.
+// UTF-16 is recognised by its BOM (Excel's "Unicode Text"); anything that is
+// not valid UTF-8 is read as Windows-1252, the ANSI code page of Indonesian and
+// Western Windows installs.
+func decodeCSVText(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return data[len(bomUTF8):], nil
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes, data)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes, data)
+	case utf8.Valid(data):
+		return data, nil
+	default:
+		return decodeWith(charmap.Windows1252.NewDecoder().Bytes, data)
+	}
+}
+
+func decodeWith(decode func([]byte) ([]byte, error), data []byte) ([]byte, error) {
+	out, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CSV text: %w", err)
+	}
+	return out, nil
+}
+
+// csvDelimiters are the separators sniffDelimiter chooses from, in order of
+// preference on a tie.
+var csvDelimiters = []rune{',', ';', '\t'}
+
+// sniffDelimiter picks the delimiter that occurs most often outside quotes in
+// the first non-empty line. Excel with a comma decimal separator, e.g. in the
+// Indonesian locale, saves "CSV" with semicolons.
+func sniffDelimiter(data []byte) rune {
+	counts := make(map[rune]int, len(csvDelimiters))
+	inQuotes := false
+	for _, r := range string(data) {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if r == '\n' || r == '\r' {
+			if len(counts) > 0 {
+				break
+			}
+			continue
+		}
+		for _, d := range csvDelimiters {
+			if r == d {
+				counts[d]++
+			}
+		}
+	}
+
+	best := csvDelimiters[0]
+	for _, d := range csvDelimiters[1:] {
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
@@ -1,15 +1,93 @@
 package util
 
 import (
+	"errors"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 var whitespaceRe = regexp.MustCompile(`\s+`)
 
-// NormalizeNIM trims and removes all whitespace from a NIM string.
-func NormalizeNIM(nim string) string {
-	nim = strings.TrimSpace(nim)
-	nim = whitespaceRe.ReplaceAllString(nim, "")
+// ErrInvalidNIMPolicy is returned by NIMPolicy.Validate.
+var ErrInvalidNIMPolicy = errors.New("invalid nim policy")
+
+const (
+	maxNIMStripChars = 32
+	maxNIMPattern    = 200
+)
+
+// NIMPolicy is an event's NIM normalization pipeline. Whitespace is always
+// removed; then StripChars are removed, letters upper-cased with CaseFold and
+// leading zeros dropped with TrimLeadingZeros. A non-empty Pattern must match
+// the whole normalized NIM. The zero value only strips whitespace.
+type NIMPolicy struct {
+	StripChars       string
+	CaseFold         bool
+	TrimLeadingZeros bool
+	Pattern          string
+}
+
+// Validate rejects policies whose pattern does not compile or that are
+// unreasonably long.
+func (p NIMPolicy) Validate() error {
+	if len(p.StripChars) > maxNIMStripChars || len(p.Pattern) > maxNIMPattern {
+		return ErrInvalidNIMPolicy
+	}
+	if p.Pattern != "" {
+		if _, err := nimPattern(p.Pattern); err != nil {
+			return ErrInvalidNIMPolicy
+		}
+	}
+	return nil
+}
+
+// Normalize runs the pipeline on a NIM as typed or imported.
+func (p NIMPolicy) Normalize(nim string) string {
+	nim = whitespaceRe.ReplaceAllString(strings.TrimSpace(nim), "")
+	if p.StripChars != "" {
+		nim = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(p.StripChars, r) {
+				return -1
+			}
+			return r
+		}, nim)
+	}
+	if p.CaseFold {
+		nim = strings.ToUpper(nim)
+	}
+	if p.TrimLeadingZeros {
+		if trimmed := strings.TrimLeft(nim, "0"); trimmed != "" {
+			nim = trimmed
+		} else if nim != "" {
+			nim = "0"
+		}
+	}
 	return nim
 }
+
+// Matches reports whether a normalized NIM satisfies the pattern; it is true
+// when there is none. A pattern that does not compile matches nothing.
+func (p NIMPolicy) Matches(nim string) bool {
+	if p.Pattern == "" {
+		return true
+	}
+	re, err := nimPattern(p.Pattern)
+	return err == nil && re.MatchString(nim)
+}
+
+// nimPatterns caches compiled patterns; rosters check thousands of NIMs
+// against the same few.
+var nimPatterns sync.Map // pattern -> *regexp.Regexp
+
+func nimPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := nimPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	nimPatterns.Store(pattern, re)
+	return re, nil
+}
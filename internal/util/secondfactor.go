@@ -0,0 +1,62 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// dobLayouts are the accepted date-of-birth spellings, ISO first.
+var dobLayouts = []string{"2006-01-02", "2-1-2006", "2/1/2006", "2.1.2006"}
+
+// NormalizeDOB parses a date of birth in any accepted layout and returns it as
+// YYYY-MM-DD, the form that is hashed and compared.
+func NormalizeDOB(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dobLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			if t.After(time.Now()) || t.Year() < 1900 {
+				return "", false
+			}
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// HashSecondFactor keys a normalized second-factor value to the server secret
+// and event, so stored hashes cannot be reversed by enumerating dates offline.
+func HashSecondFactor(secret, eventID, value string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(eventID + "|" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySecondFactor compares a submitted value with its stored hash in constant time.
+func VerifySecondFactor(secret, eventID, value, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return hmac.Equal([]byte(HashSecondFactor(secret, eventID, value)), []byte(hash))
+}
+
+// PhoneLast4 returns the last four digits of a stored E.164 phone number, the
+// value the PHONE_LAST4 second factor hashes, or "" for a shorter number.
+func PhoneLast4(phone string) string {
+	if len(phone) < 4 {
+		return ""
+	}
+	return phone[len(phone)-4:]
+}
+
+// VerifyPhoneLast4 compares submitted digits with a stored phone hash in
+// constant time.
+func VerifyPhoneLast4(secret, eventID, digits, hash string) bool {
+	digits = strings.TrimSpace(digits)
+	if len(digits) != 4 {
+		return false
+	}
+	return VerifySecondFactor(secret, eventID, digits, hash)
+}
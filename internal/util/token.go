@@ -2,21 +2,96 @@ package util
 
 import (
 	"crypto/rand"
+	"errors"
+	"math"
 	"math/big"
+	"strings"
 )
 
-const tokenCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-const tokenLength = 8
+const (
+	// LegacyTokenAlphabet is the original A-Z0-9 set used by older events.
+	LegacyTokenAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// UnambiguousTokenAlphabet drops 0/O, 1/I and L, which voters mistype.
+	UnambiguousTokenAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
 
-// GenerateToken creates a cryptographically secure random 8-char uppercase alphanumeric token.
-func GenerateToken() (string, error) {
-	b := make([]byte, tokenLength)
+	minTokenLength  = 6
+	maxTokenLength  = 24
+	minTokenEntropy = 30 // bits
+)
+
+var ErrInvalidTokenPolicy = errors.New("invalid token policy")
+
+// TokenPolicy describes the token format of one event. Length counts the
+// random characters; CheckChar appends one Luhn mod N check character.
+type TokenPolicy struct {
+	Length    int
+	Alphabet  string
+	CheckChar bool
+}
+
+// DefaultTokenPolicy is applied to newly created events.
+var DefaultTokenPolicy = TokenPolicy{Length: 8, Alphabet: UnambiguousTokenAlphabet, CheckChar: true}
+
+// Validate rejects policies that are malformed or too easy to guess.
+func (p TokenPolicy) Validate() error {
+	if p.Length < minTokenLength || p.Length > maxTokenLength {
+		return ErrInvalidTokenPolicy
+	}
+	if len(p.Alphabet) < 10 {
+		return ErrInvalidTokenPolicy
+	}
+	seen := make(map[rune]bool, len(p.Alphabet))
+	for _, r := range p.Alphabet {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') || seen[r] {
+			return ErrInvalidTokenPolicy
+		}
+		seen[r] = true
+	}
+	if float64(p.Length)*math.Log2(float64(len(p.Alphabet))) < minTokenEntropy {
+		return ErrInvalidTokenPolicy
+	}
+	return nil
+}
+
+// TokenLen is the full printed token length, including the check character.
+func (p TokenPolicy) TokenLen() int {
+	if p.CheckChar {
+		return p.Length + 1
+	}
+	return p.Length
+}
+
+// GenerateToken creates a cryptographically secure random token following the policy.
+func GenerateToken(p TokenPolicy) (string, error) {
+	b := make([]byte, p.Length)
 	for i := range b {
-		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(tokenCharset))))
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(p.Alphabet))))
 		if err != nil {
 			return "", err
 		}
-		b[i] = tokenCharset[n.Int64()]
+		b[i] = p.Alphabet[n.Int64()]
+	}
+	token := string(b)
+	if p.CheckChar {
+		token += string(luhnCheckChar(token, p.Alphabet))
+	}
+	return token, nil
+}
+
+// luhnCheckChar computes the Luhn mod N check character of payload.
+// Every rune of payload must be in alphabet.
+func luhnCheckChar(payload, alphabet string) byte {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, payload[i])
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+		sum += addend/n + addend%n
 	}
-	return string(b), nil
+	return alphabet[(n-sum%n)%n]
 }
@@ -1,18 +1,51 @@
 package util
 
 import (
-	"regexp"
 	"strings"
 )
 
-var tokenRegex = regexp.MustCompile(`^[A-Z0-9]{8}$`)
+// lookalikes maps characters voters confuse to the one the alphabet may use instead.
+var lookalikes = map[rune]rune{'O': '0', '0': 'O', 'I': '1', 'L': '1', '1': 'I'}
 
-// NormalizeToken uppercases and trims a token string.
-func NormalizeToken(token string) string {
-	return strings.ToUpper(strings.TrimSpace(token))
+// NormalizeToken uppercases a token, strips whitespace and dash separators, and
+// folds look-alike characters (O/0, I/1/L) onto whichever one the policy's
+// alphabet actually contains.
+func NormalizeToken(token string, p TokenPolicy) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(token) {
+		if r == '-' || r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			continue
+		}
+		if !strings.ContainsRune(p.Alphabet, r) {
+			if alt, ok := lookalikes[r]; ok && strings.ContainsRune(p.Alphabet, alt) {
+				r = alt
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
-// ValidateToken checks if a token matches the expected format.
-func ValidateToken(token string) bool {
-	return tokenRegex.MatchString(token)
+// ValidateToken checks a normalized token against the policy's length,
+// alphabet and check character, so typos are rejected before any DB lookup.
+func ValidateToken(token string, p TokenPolicy) bool {
+	if len(token) != p.TokenLen() {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		if strings.IndexByte(p.Alphabet, token[i]) < 0 {
+			return false
+		}
+	}
+	if p.CheckChar {
+		payload := token[:p.Length]
+		return luhnCheckChar(payload, p.Alphabet) == token[p.Length]
+	}
+	return true
+}
+
+// ValidUUID reports whether s is a canonical hyphenated UUID.
+func ValidUUID(s string) bool {
+	_, err := uuidBytes(s)
+	return err == nil && len(s) == 36
 }
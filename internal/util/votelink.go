@@ -0,0 +1,67 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var ErrMalformedVoteLink = errors.New("malformed voting link")
+
+const voteLinkMACSize = 16
+
+// SignVoteLink returns the opaque credential for a per-voter voting link. It
+// packs the token row ID with an HMAC over the event, token ID and token
+// value, so the credential dies with the token: once the token is used,
+// revoked or reissued the stored value no longer verifies.
+func SignVoteLink(secret, eventID, tokenID, token string) (string, error) {
+	id, err := uuidBytes(tokenID)
+	if err != nil {
+		return "", err
+	}
+	buf := append(id, voteLinkMAC(secret, eventID, tokenID, token)...)
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ParseVoteLink extracts the token ID from a credential. The caller must load
+// the token and confirm it with VerifyVoteLink.
+func ParseVoteLink(credential string) (tokenID string, err error) {
+	buf, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(credential))
+	if err != nil || len(buf) != 16+voteLinkMACSize {
+		return "", ErrMalformedVoteLink
+	}
+	h := hex.EncodeToString(buf[:16])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32], nil
+}
+
+// VerifyVoteLink checks the credential's MAC against the stored token in constant time.
+func VerifyVoteLink(secret, eventID, tokenID, token, credential string) bool {
+	buf, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(credential))
+	if err != nil || len(buf) != 16+voteLinkMACSize {
+		return false
+	}
+	return hmac.Equal(buf[16:], voteLinkMAC(secret, eventID, tokenID, token))
+}
+
+// VoteLinkURL builds the voter-facing URL for a credential.
+func VoteLinkURL(baseURL, eventID, credential string) string {
+	return strings.TrimRight(baseURL, "/") + "/" + eventID + "?link=" + url.QueryEscape(credential)
+}
+
+func voteLinkMAC(secret, eventID, tokenID, token string) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte("vote-link|" + eventID + "|" + tokenID + "|" + token))
+	return m.Sum(nil)[:voteLinkMACSize]
+}
+
+func uuidBytes(id string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil || len(b) != 16 {
+		return nil, errors.New("invalid uuid")
+	}
+	return b, nil
+}
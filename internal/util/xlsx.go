@@ -0,0 +1,123 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxHeaderScanRows is how far down a sheet the header row is searched for,
+// so title and note rows above the roster table are skipped.
+const xlsxHeaderScanRows = 20
+
+// ParseVotersXLSX parses a voters roster from one sheet of an .xlsx workbook,
+// with the same columns and validation as ParseVotersCSV. An empty sheet name
+// selects the first sheet. Rejected rows carry the sheet's own row numbers.
+func ParseVotersXLSX(r io.Reader, sheet string, nimPolicy NIMPolicy) (*CSVParseResult, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX file: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("XLSX file has no sheets")
+	}
+	if sheet == "" {
+		sheet = sheets[0]
+	} else if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+		return nil, fmt.Errorf("sheet %q not found (available: %s)", sheet, strings.Join(sheets, ", "))
+	}
+
+	// Formatted values keep what the user sees, e.g. leading zeros from a
+	// "00000" number format; raw values recover digits that the display
+	// format collapses into scientific notation.
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheet, err)
+	}
+	raw, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheet, err)
+	}
+
+	headerIdx := -1
+	var cols rosterColumns
+	for i := 0; i < len(rows) && i < xlsxHeaderScanRows; i++ {
+		if c, ok := findRosterColumns(rows[i]); ok {
+			headerIdx, cols = i, c
+			break
+		}
+	}
+	if headerIdx < 0 {
+		return nil, fmt.Errorf("sheet %q must have 'full_name' and 'nim' columns in its first %d rows", sheet, xlsxHeaderScanRows)
+	}
+
+	i := headerIdx
+	next := func() ([]string, error) {
+		i++
+		if i >= len(rows) {
+			return nil, io.EOF
+		}
+		record := make([]string, 0, len(rows[i]))
+		blank := true
+		for j, v := range rows[i] {
+			var rawValue string
+			if i < len(raw) && j < len(raw[i]) {
+				rawValue = raw[i][j]
+			}
+			if j == cols.dob {
+				v = xlsxDateText(v, rawValue)
+			} else {
+				v = xlsxCellText(v, rawValue)
+			}
+			if strings.TrimSpace(v) != "" {
+				blank = false
+			}
+			record = append(record, v)
+		}
+		if blank {
+			// Spacer rows inside the table are skipped, not rejected.
+			return []string{}, nil
+		}
+		return record, nil
+	}
+
+	return parseRoster(cols, headerIdx+1, nimPolicy, next)
+}
+
+// xlsxCellText returns the text of a cell. A number shown in scientific
+// notation (Excel's General format for long digit strings such as NIMs) is
+// expanded from the raw value so no digits are lost.
+func xlsxCellText(formatted, raw string) string {
+	if !strings.ContainsAny(formatted, "Ee") {
+		return formatted
+	}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(formatted), 64); err != nil {
+		return formatted
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return formatted
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// xlsxDateText returns a date cell as YYYY-MM-DD. Dates are stored as serial
+// numbers and shown in the workbook's locale format, which NormalizeDOB
+// cannot tell apart from day-first input; text cells are left as typed.
+func xlsxDateText(formatted, raw string) string {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || formatted == raw {
+		return formatted
+	}
+	t, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return formatted
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE voters ADD COLUMN email TEXT;
+ALTER TABLE voters ADD COLUMN phone VARCHAR(32);
+
+CREATE TABLE token_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    voter_id UUID NOT NULL REFERENCES voters(id) ON DELETE CASCADE,
+    channel TEXT NOT NULL CHECK (channel IN ('EMAIL')),
+    recipient TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'QUEUED'
+        CHECK (status IN ('QUEUED','SENT','FAILED','BOUNCED')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    queued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE token_deliveries ADD CONSTRAINT uq_token_deliveries_voter_channel UNIQUE (voter_id, channel);
+CREATE INDEX idx_token_deliveries_event_status ON token_deliveries(event_id, status);
+
+-- +goose Down
+DROP TABLE IF EXISTS token_deliveries;
+ALTER TABLE voters DROP COLUMN IF EXISTS phone;
+ALTER TABLE voters DROP COLUMN IF EXISTS email;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE token_deliveries DROP CONSTRAINT token_deliveries_channel_check;
+ALTER TABLE token_deliveries ADD CONSTRAINT token_deliveries_channel_check
+    CHECK (channel IN ('EMAIL','WHATSAPP','SMS'));
+
+-- +goose Down
+DELETE FROM token_deliveries WHERE channel IN ('WHATSAPP','SMS');
+ALTER TABLE token_deliveries DROP CONSTRAINT token_deliveries_channel_check;
+ALTER TABLE token_deliveries ADD CONSTRAINT token_deliveries_channel_check
+    CHECK (channel IN ('EMAIL'));
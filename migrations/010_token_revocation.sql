@@ -0,0 +1,15 @@
+-- +goose Up
+-- A voter keeps every revoked token as history but may hold at most one live
+-- (ACTIVE or USED) token at a time.
+ALTER TABLE voter_tokens DROP CONSTRAINT voter_tokens_voter_id_key;
+CREATE UNIQUE INDEX uq_voter_tokens_voter_live ON voter_tokens(voter_id) WHERE status <> 'REVOKED';
+
+ALTER TABLE voter_tokens ADD COLUMN revoked_at TIMESTAMPTZ;
+ALTER TABLE voter_tokens ADD COLUMN revoke_reason TEXT;
+
+-- +goose Down
+DELETE FROM voter_tokens WHERE status = 'REVOKED';
+ALTER TABLE voter_tokens DROP COLUMN IF EXISTS revoke_reason;
+ALTER TABLE voter_tokens DROP COLUMN IF EXISTS revoked_at;
+DROP INDEX IF EXISTS uq_voter_tokens_voter_live;
+ALTER TABLE voter_tokens ADD CONSTRAINT voter_tokens_voter_id_key UNIQUE (voter_id);
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Existing events keep the original 8-char A-Z0-9 format; new events get
+-- their policy from the application.
+ALTER TABLE events ADD COLUMN token_length INT NOT NULL DEFAULT 8;
+ALTER TABLE events ADD COLUMN token_alphabet TEXT NOT NULL DEFAULT 'ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789';
+ALTER TABLE events ADD COLUMN token_check_char BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE voter_tokens ALTER COLUMN token TYPE VARCHAR(32);
+
+-- +goose Down
+ALTER TABLE voter_tokens ALTER COLUMN token TYPE CHAR(8);
+ALTER TABLE events DROP COLUMN IF EXISTS token_check_char;
+ALTER TABLE events DROP COLUMN IF EXISTS token_alphabet;
+ALTER TABLE events DROP COLUMN IF EXISTS token_length;
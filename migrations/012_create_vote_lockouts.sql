@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE vote_lockouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('TOKEN','NIM')),
+    subject TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    lockouts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE vote_lockouts ADD CONSTRAINT uq_vote_lockouts_subject UNIQUE (event_id, kind, subject);
+CREATE INDEX idx_vote_lockouts_event_locked ON vote_lockouts(event_id, locked_until);
+
+-- +goose Down
+DROP TABLE IF EXISTS vote_lockouts;
@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE events ADD COLUMN registration_open BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE voter_registrations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    full_name TEXT NOT NULL,
+    nim_raw VARCHAR(50) NOT NULL,
+    nim_normalized VARCHAR(50) NOT NULL,
+    class_name TEXT,
+    email TEXT,
+    phone VARCHAR(32),
+    status TEXT NOT NULL DEFAULT 'PENDING'
+        CHECK (status IN ('PENDING','APPROVED','REJECTED')),
+    reject_reason TEXT,
+    voter_id UUID REFERENCES voters(id) ON DELETE SET NULL,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One pending entry per NIM; rejected applicants may register again.
+CREATE UNIQUE INDEX uq_voter_registrations_pending_nim ON voter_registrations(event_id, nim_normalized) WHERE status = 'PENDING';
+CREATE INDEX idx_voter_registrations_event_status ON voter_registrations(event_id, status, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS voter_registrations;
+ALTER TABLE events DROP COLUMN IF EXISTS registration_open;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE events ADD COLUMN second_factor TEXT
+    CHECK (second_factor IN ('DOB','PHONE_LAST4'));
+
+-- HMAC of the normalized date of birth; the plain value is never stored.
+ALTER TABLE voters ADD COLUMN dob_hash TEXT;
+ALTER TABLE voter_registrations ADD COLUMN dob_hash TEXT;
+
+-- +goose Down
+ALTER TABLE voter_registrations DROP COLUMN IF EXISTS dob_hash;
+ALTER TABLE voters DROP COLUMN IF EXISTS dob_hash;
+ALTER TABLE events DROP COLUMN IF EXISTS second_factor;
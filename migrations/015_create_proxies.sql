@@ -0,0 +1,27 @@
+-- +goose Up
+-- proxy_cap is how many members one holder may represent; 0 disables proxies.
+ALTER TABLE events ADD COLUMN proxy_cap INT NOT NULL DEFAULT 1 CHECK (proxy_cap >= 0);
+
+CREATE TABLE proxies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    grantor_voter_id UUID NOT NULL REFERENCES voters(id) ON DELETE CASCADE,
+    holder_voter_id UUID NOT NULL REFERENCES voters(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'ACTIVE'
+        CHECK (status IN ('ACTIVE','USED','REVOKED')),
+    note TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    CHECK (grantor_voter_id <> holder_voter_id)
+);
+
+-- A member delegates at most once at a time; revoked proxies can be replaced.
+CREATE UNIQUE INDEX uq_proxies_grantor_live ON proxies(grantor_voter_id) WHERE status <> 'REVOKED';
+CREATE INDEX idx_proxies_event ON proxies(event_id, created_at);
+CREATE INDEX idx_proxies_holder ON proxies(holder_voter_id, status);
+
+-- +goose Down
+DROP TABLE IF EXISTS proxies;
+ALTER TABLE events DROP COLUMN IF EXISTS proxy_cap;
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    mode TEXT NOT NULL DEFAULT 'INSERT'
+        CHECK (mode IN ('INSERT','SYNC')),
+    disable_missing BOOLEAN NOT NULL DEFAULT false,
+    file_name TEXT NOT NULL,
+    is_xlsx BOOLEAN NOT NULL DEFAULT false,
+    sheet TEXT,
+    file_data BYTEA NOT NULL,
+    status TEXT NOT NULL DEFAULT 'QUEUED'
+        CHECK (status IN ('QUEUED','RUNNING','SUCCEEDED','FAILED')),
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    rejected JSONB NOT NULL DEFAULT '[]',
+    result JSONB,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+-- Jobs for one event run one at a time.
+CREATE UNIQUE INDEX uq_import_jobs_event_running ON import_jobs(event_id) WHERE status = 'RUNNING';
+CREATE INDEX idx_import_jobs_event_created ON import_jobs(event_id, created_at);
+CREATE INDEX idx_import_jobs_status ON import_jobs(status);
+
+-- +goose Down
+DROP TABLE IF EXISTS import_jobs;
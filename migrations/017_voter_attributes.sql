@@ -0,0 +1,12 @@
+-- +goose Up
+-- voter_attributes are the custom attribute keys an event defines for its
+-- voters, e.g. faculty or campus; the values live in voters.attributes.
+ALTER TABLE events ADD COLUMN voter_attributes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE voters ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_voters_attributes ON voters USING GIN (attributes jsonb_path_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_voters_attributes;
+ALTER TABLE voters DROP COLUMN IF EXISTS attributes;
+ALTER TABLE events DROP COLUMN IF EXISTS voter_attributes;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Per-event NIM normalization; the defaults keep whitespace-only stripping.
+ALTER TABLE events ADD COLUMN nim_strip_chars TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN nim_case_fold BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE events ADD COLUMN nim_trim_zeros BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE events ADD COLUMN nim_pattern TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE events DROP COLUMN IF EXISTS nim_pattern;
+ALTER TABLE events DROP COLUMN IF EXISTS nim_trim_zeros;
+ALTER TABLE events DROP COLUMN IF EXISTS nim_case_fold;
+ALTER TABLE events DROP COLUMN IF EXISTS nim_strip_chars;
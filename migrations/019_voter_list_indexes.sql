@@ -0,0 +1,14 @@
+-- +goose Up
+-- Keyset pagination of the voter list: one index per sort key, with id as the
+-- tie-breaker. Nullable keys are indexed through the same COALESCE the list
+-- query sorts on. Sorting by nim uses uq_voters_event_nim.
+CREATE INDEX idx_voters_event_created_id ON voters(event_id, created_at, id);
+CREATE INDEX idx_voters_event_full_name_id ON voters(event_id, full_name, id);
+CREATE INDEX idx_voters_event_class_id ON voters(event_id, (COALESCE(class_name, '')), id);
+CREATE INDEX idx_voters_event_voted_at_id ON voters(event_id, (COALESCE(voted_at, '-infinity'::timestamptz)), id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_voters_event_voted_at_id;
+DROP INDEX IF EXISTS idx_voters_event_class_id;
+DROP INDEX IF EXISTS idx_voters_event_full_name_id;
+DROP INDEX IF EXISTS idx_voters_event_created_id;
@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE member_directories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_member_directories_owner ON member_directories(owner_user_id);
+
+-- Members are keyed on the NIM with whitespace removed. Events copy members
+-- into their own voters table, so edits here never reach an existing roster.
+CREATE TABLE directory_members (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    directory_id UUID NOT NULL REFERENCES member_directories(id) ON DELETE CASCADE,
+    full_name TEXT NOT NULL,
+    nim_raw TEXT NOT NULL,
+    nim_normalized TEXT NOT NULL,
+    class_name TEXT,
+    email TEXT,
+    phone TEXT,
+    dob DATE,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT uq_directory_members_nim UNIQUE (directory_id, nim_normalized)
+);
+
+CREATE INDEX idx_directory_members_class ON directory_members(directory_id, class_name);
+CREATE INDEX idx_directory_members_attributes ON directory_members USING GIN (attributes);
+
+-- +goose Down
+DROP TABLE IF EXISTS directory_members;
+DROP TABLE IF EXISTS member_directories;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- A template is a snapshot of an event's description, settings and optionally
+-- its slates, used to start new events. It does not follow later edits.
+CREATE TABLE event_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    source_event_id UUID REFERENCES events(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    settings JSONB NOT NULL,
+    slates JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT uq_event_templates_owner_name UNIQUE (owner_user_id, name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS event_templates;
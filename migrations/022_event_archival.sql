@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE events ADD COLUMN locked_at TIMESTAMPTZ;
+ALTER TABLE events ADD COLUMN archived_at TIMESTAMPTZ;
+UPDATE events SET locked_at = updated_at WHERE status = 'LOCKED';
+
+CREATE INDEX idx_events_locked_unarchived ON events(locked_at) WHERE status = 'LOCKED' AND archived_at IS NULL;
+
+-- Account-level trail of actions that outlive an event: archival, retention
+-- purges and deletion. event_id has no foreign key so entries survive the
+-- event.
+CREATE TABLE account_audit_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_user_id UUID,
+    event_id UUID,
+    action TEXT NOT NULL,
+    meta JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_account_audit_logs_user_created ON account_audit_logs(user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS account_audit_logs;
+DROP INDEX IF EXISTS idx_events_locked_unarchived;
+ALTER TABLE events DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE events DROP COLUMN IF EXISTS locked_at;
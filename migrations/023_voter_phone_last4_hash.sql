@@ -0,0 +1,12 @@
+-- +goose Up
+-- HMAC of the last four digits of the phone number, keyed like dob_hash, so
+-- the PHONE_LAST4 second factor is checked against a per-event hash. Existing
+-- rows are filled in at startup, where the key is available.
+ALTER TABLE voters ADD COLUMN phone_last4_hash TEXT;
+
+CREATE INDEX idx_voters_phone_last4_missing ON voters(event_id)
+    WHERE phone IS NOT NULL AND phone_last4_hash IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_voters_phone_last4_missing;
+ALTER TABLE voters DROP COLUMN IF EXISTS phone_last4_hash;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Dates of birth are only ever stored as per-event hashes (see 014). A
+-- directory cannot hold one, since the hash for an event is keyed to it, so
+-- events with a DOB second factor take the dob column from a roster import.
+ALTER TABLE directory_members DROP COLUMN IF EXISTS dob;
+
+-- +goose Down
+ALTER TABLE directory_members ADD COLUMN dob DATE;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A RUNNING job is leased by the process running it, which renews
+-- heartbeat_at while it works. Only jobs whose lease has expired are put back
+-- in the queue, so a restart never takes over a job another instance is
+-- still running.
+ALTER TABLE import_jobs ADD COLUMN worker_id TEXT;
+ALTER TABLE import_jobs ADD COLUMN heartbeat_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE import_jobs DROP COLUMN IF EXISTS worker_id;